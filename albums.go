@@ -0,0 +1,224 @@
+package main
+
+import (
+	"cmp"
+	"fmt"
+	"io/fs"
+	"log"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+type albumTrack struct {
+	path   string
+	tags   fileTags
+	number int
+}
+
+// tracks grouped by album and artist tags
+type album struct {
+	artist string
+	title  string
+	tracks []albumTrack
+	// tracks are listed under the album
+	expanded bool
+}
+
+// result of scanning directory for albums
+type albumsScannedMsg struct {
+	root   string
+	albums []album
+	err    error
+}
+
+// reads tags of all tracks inside the directory and groups them into albums.
+// tracks without album tag are grouped by their directory
+func scanAlbums(root string) ([]album, error) {
+	byKey := make(map[string]*album)
+	err := walkDir(root, func(trackPath string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if entry.IsDir() || !isSupportedFormat(trackPath) {
+			return nil
+		}
+		tags, err := readFileTags(trackPath)
+		if err != nil {
+			log.Printf("failed to read tags of %s: %v", trackPath, err)
+		}
+		title := tags.album
+		if title == "" {
+			title = filepath.Base(filepath.Dir(trackPath))
+		}
+		key := tags.artist + "\x00" + title
+		if byKey[key] == nil {
+			byKey[key] = &album{artist: tags.artist, title: title}
+		}
+		byKey[key].tracks = append(byKey[key].tracks, albumTrack{path: trackPath, tags: tags, number: tags.number})
+		return nil
+	})
+	albums := make([]album, 0, len(byKey))
+	for _, a := range byKey {
+		slices.SortFunc(a.tracks, func(x, y albumTrack) int {
+			return cmp.Or(compareTrackNumbers(x.number, y.number), strings.Compare(x.path, y.path))
+		})
+		albums = append(albums, *a)
+	}
+	slices.SortFunc(albums, func(x, y album) int {
+		return cmp.Or(strings.Compare(strings.ToLower(x.artist), strings.ToLower(y.artist)),
+			strings.Compare(strings.ToLower(x.title), strings.ToLower(y.title)))
+	})
+	return albums, err
+}
+
+// scans directory in background, so UI stays responsive on big libraries
+func scanAlbumsCmd(root string) tea.Cmd {
+	return func() tea.Msg {
+		albums, err := scanAlbums(root)
+		return albumsScannedMsg{root: root, albums: albums, err: err}
+	}
+}
+
+func (al album) String() string {
+	name := al.title
+	if al.artist != "" {
+		name = al.artist + " - " + al.title
+	}
+	return fmt.Sprintf("%s (%d)", name, len(al.tracks))
+}
+
+// row of the album view, track is -1 for the row of the album itself
+type albumRow struct {
+	album int
+	track int
+}
+
+func (a appState) albumRows() []albumRow {
+	rows := []albumRow{}
+	for i, al := range a.albums {
+		rows = append(rows, albumRow{album: i, track: -1})
+		if !al.expanded {
+			continue
+		}
+		for j := range al.tracks {
+			rows = append(rows, albumRow{album: i, track: j})
+		}
+	}
+	return rows
+}
+
+// opens album view of the current directory, tags are read on the first opening
+func (a appState) openAlbumView() (appState, tea.Cmd) {
+	a.albumView = true
+	if a.albumsRoot == a.currentDir {
+		return a, nil
+	}
+	a.albumsRoot = a.currentDir
+	a.albums = nil
+	a.albumCursor = 0
+	return a, scanAlbumsCmd(a.currentDir)
+}
+
+// keys that act on entries of the browser do nothing in album view
+var browserKeys = []string{"+", "n", "d", "D", "e", "a", "{", "}", "-", ".", "ctrl+r", "P", "/", ":"}
+
+// handles keys of the album view. returns false for keys that work like in the browser
+func (a appState) updateAlbumView(msg tea.KeyMsg) (appState, bool) {
+	rows := a.albumRows()
+	switch msg.String() {
+	case "b", "esc":
+		a.albumView = false
+	case "up", "k":
+		if a.albumCursor > 0 {
+			a.albumCursor--
+		} else if a.config.WrapCursor {
+			a.albumCursor = max(len(rows)-1, 0)
+		}
+	case "down", "j":
+		if a.albumCursor < len(rows)-1 {
+			a.albumCursor++
+		} else if a.config.WrapCursor {
+			a.albumCursor = 0
+		}
+	case "enter":
+		if len(rows) == 0 {
+			break
+		}
+		row := rows[a.albumCursor]
+		// albums are copied, so expanding one doesn't change the scanned list
+		a.albums = slices.Clone(a.albums)
+		a.albums[row.album].expanded = !a.albums[row.album].expanded
+		if !a.albums[row.album].expanded {
+			a.albumCursor = slices.Index(a.albumRows(), albumRow{album: row.album, track: -1})
+		}
+	case " ":
+		if len(rows) == 0 {
+			break
+		}
+		row := rows[a.albumCursor]
+		tracks := a.albums[row.album].tracks
+		if row.track != -1 {
+			tracks = tracks[row.track : row.track+1]
+		}
+		a.status = a.tracksQueue.addAlbumTracks(tracks).String()
+		a.tracksQueue.play()
+	default:
+		return a, slices.Contains(browserKeys, msg.String())
+	}
+	return a, true
+}
+
+// queues tracks in the given order, already queued tracks are skipped
+func (s *tracksQueue) addAlbumTracks(tracks []albumTrack) addSummary {
+	summary := addSummary{}
+	for _, at := range tracks {
+		s.addFile(at.path, &summary)
+	}
+	return summary
+}
+
+// renders albums and tracks of expanded ones around the cursor
+func (a appState) albumListView() string {
+	if a.albums == nil {
+		return "reading tags of " + a.albumsRoot + "...\n"
+	}
+	if len(a.albums) == 0 {
+		return "no tracks in " + a.albumsRoot + "\n"
+	}
+	rows := a.albumRows()
+	start := max(0, min(a.albumCursor-a.listSize/2, len(rows)-a.listSize))
+	end := min(len(rows), start+a.listSize)
+	s := ""
+	for i := start; i < end; i++ {
+		cursor := " "
+		if i == a.albumCursor {
+			cursor = cursorStyle.Render(">")
+		}
+		row := rows[i]
+		al := a.albums[row.album]
+		if row.track == -1 {
+			mark := "+"
+			if al.expanded {
+				mark = "-"
+			}
+			s += fmt.Sprintf("%s %s %s\n", cursor, mark, al)
+			continue
+		}
+		t := al.tracks[row.track]
+		name := t.tags.title
+		if name == "" {
+			name = filepath.Base(t.path)
+		}
+		if t.number != 0 {
+			name = fmt.Sprintf("%2d. %s", t.number, name)
+		}
+		if a.tracksQueue.hasTrack(t.path) {
+			name = queuedStyle.Render(name)
+		}
+		s += fmt.Sprintf("%s     %s\n", cursor, name)
+	}
+	return s
+}
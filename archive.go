@@ -0,0 +1,151 @@
+package main
+
+import (
+	"archive/zip"
+	"bytes"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// archives are browsed like directories. path inside archive is written
+// after the archive path, for example /music/album.zip/cd1/track.mp3
+func isArchive(name string) bool {
+	return fileFormat(name) == "zip"
+}
+
+// splits virtual path into archive path and slash separated path inside it.
+// returns false if path is not inside an archive
+func splitArchivePath(virtualPath string) (string, string, bool) {
+	for dir := virtualPath; ; dir = filepath.Dir(dir) {
+		if isArchive(dir) {
+			if info, err := os.Stat(dir); err == nil && info.Mode().IsRegular() {
+				inner, err := filepath.Rel(dir, virtualPath)
+				if err != nil {
+					return "", "", false
+				}
+				return dir, filepath.ToSlash(inner), true
+			}
+		}
+		if filepath.Dir(dir) == dir {
+			return "", "", false
+		}
+	}
+}
+
+// file system the browser reads directories from, replaced in tests
+type browseFS interface {
+	ReadDir(name string) ([]fs.DirEntry, error)
+	Stat(name string) (fs.FileInfo, error)
+}
+
+type osFS struct{}
+
+func (osFS) ReadDir(name string) ([]fs.DirEntry, error) { return os.ReadDir(name) }
+func (osFS) Stat(name string) (fs.FileInfo, error)      { return os.Stat(name) }
+
+var browserFS browseFS = osFS{}
+
+// reads directory which may be inside an archive
+func readDir(dirPath string) ([]fs.DirEntry, error) {
+	archive, inner, ok := splitArchivePath(dirPath)
+	if !ok {
+		return browserFS.ReadDir(dirPath)
+	}
+	zr, err := zip.OpenReader(archive)
+	if err != nil {
+		return nil, err
+	}
+	defer zr.Close()
+	return fs.ReadDir(zr, inner)
+}
+
+// returns whether path is a directory or an archive that can be browsed
+func isBrowsable(virtualPath string) (bool, error) {
+	archive, inner, ok := splitArchivePath(virtualPath)
+	if !ok {
+		info, err := browserFS.Stat(virtualPath)
+		if err != nil {
+			return false, err
+		}
+		return info.IsDir(), nil
+	}
+	if inner == "." {
+		return true, nil
+	}
+	zr, err := zip.OpenReader(archive)
+	if err != nil {
+		return false, err
+	}
+	defer zr.Close()
+	info, err := fs.Stat(zr, inner)
+	if err != nil {
+		return false, err
+	}
+	return info.IsDir(), nil
+}
+
+// walks directory tree which may be inside an archive.
+// paths passed to fn are virtual paths
+func walkDir(root string, fn fs.WalkDirFunc) error {
+	archive, inner, ok := splitArchivePath(root)
+	if !ok {
+		return filepath.WalkDir(root, fn)
+	}
+	zr, err := zip.OpenReader(archive)
+	if err != nil {
+		return err
+	}
+	defer zr.Close()
+	return fs.WalkDir(zr, inner, func(p string, entry fs.DirEntry, err error) error {
+		return fn(filepath.Join(archive, filepath.FromSlash(p)), entry, err)
+	})
+}
+
+// in-memory file. decoder needs io.Seeker to be able to seek
+type memFile struct {
+	*bytes.Reader
+}
+
+func (memFile) Close() error {
+	return nil
+}
+
+// opens track file. file inside an archive is read into memory
+func openTrackFile(trackPath string) (io.ReadCloser, error) {
+	archive, inner, ok := splitArchivePath(trackPath)
+	if !ok {
+		f, err := os.Open(trackPath)
+		if err != nil {
+			return nil, err
+		}
+		fileStat, err := f.Stat()
+		if err != nil {
+			f.Close()
+			return nil, err
+		}
+		if fileStat.IsDir() {
+			f.Close()
+			return nil, errFileIsNotTrack
+		}
+		return f, nil
+	}
+	zr, err := zip.OpenReader(archive)
+	if err != nil {
+		return nil, err
+	}
+	defer zr.Close()
+	info, err := fs.Stat(zr, inner)
+	if err != nil {
+		return nil, err
+	}
+	if info.IsDir() {
+		return nil, errFileIsNotTrack
+	}
+	data, err := fs.ReadFile(zr, inner)
+	if err != nil {
+		return nil, err
+	}
+	return memFile{bytes.NewReader(data)}, nil
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"slices"
+	"time"
+
+	"github.com/gopxl/beep/v2"
+)
+
+// chapter of a long track, for example of an audiobook
+type chapter struct {
+	title string
+	// first sample of the chapter in track stream
+	start int
+}
+
+// reads chapters from ID3v2 CHAP frames.
+// returns nil if there are no chapters
+func id3Chapters(tag id3Tag, sampleRate beep.SampleRate) []chapter {
+	chapters := []chapter{}
+	for _, frame := range tag.frames {
+		if frame.id != "CHAP" {
+			continue
+		}
+		// element id ends with zero byte, start and end times in ms and byte offsets follow
+		elementID, rest, ok := bytes.Cut(frame.data, []byte{0})
+		if !ok || len(rest) < 16 {
+			continue
+		}
+		startMs := binary.BigEndian.Uint32(rest)
+		title := string(elementID)
+		for _, subFrame := range id3Frames(rest[16:], tag.version) {
+			if subFrame.id == "TIT2" {
+				title = decodeID3Text(subFrame.data)
+			}
+		}
+		chapters = append(chapters, chapter{
+			title: title,
+			start: sampleRate.N(time.Duration(startMs) * time.Millisecond),
+		})
+	}
+	if len(chapters) == 0 {
+		return nil
+	}
+	slices.SortFunc(chapters, func(a, b chapter) int {
+		return a.start - b.start
+	})
+	return chapters
+}
+
+// returns index of the chapter playing at position or -1 if track has no chapters
+func (t track) chapterAt(pos int) int {
+	index := -1
+	for i, ch := range t.chapters {
+		if ch.start <= pos {
+			index = i
+		}
+	}
+	return index
+}
+
+// seeks to the chapter of the current track with given offset from the playing one.
+// returns false if there is no such chapter
+func (s *tracksQueue) seekChapter(delta int) bool {
+	currentTrack, ok := s.getCurrentTrack()
+	if !ok || len(currentTrack.chapters) == 0 {
+		return false
+	}
+	pos, _, _ := s.position()
+	index := currentTrack.chapterAt(pos) + delta
+	if index < 0 || index >= len(currentTrack.chapters) {
+		return false
+	}
+	return s.seek(currentTrack.chapters[index].start) == nil
+}
@@ -0,0 +1,34 @@
+package main
+
+import (
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// source of time for time-driven features, so they can run against a fake one
+type clock interface {
+	Now() time.Time
+	// delivers the current time once the duration passes
+	After(d time.Duration) <-chan time.Time
+}
+
+// clock backed by the system time
+type realClock struct{}
+
+func (realClock) Now() time.Time {
+	return time.Now()
+}
+
+func (realClock) After(d time.Duration) <-chan time.Time {
+	return time.After(d)
+}
+
+// same as tea.Tick, but waits on the clock. the wait starts when the command
+// is made, not when it runs
+func clockTick(c clock, d time.Duration, fn func(time.Time) tea.Msg) tea.Cmd {
+	ch := c.After(d)
+	return func() tea.Msg {
+		return fn(<-ch)
+	}
+}
@@ -0,0 +1,157 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"sync"
+	"testing"
+	"time"
+)
+
+// clock that moves only when told to
+type fakeClock struct {
+	mu      sync.Mutex
+	now     time.Time
+	waiters []fakeWaiter
+}
+
+type fakeWaiter struct {
+	at time.Time
+	ch chan time.Time
+}
+
+func newFakeClock() *fakeClock {
+	return &fakeClock{now: time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)}
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *fakeClock) After(d time.Duration) <-chan time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	ch := make(chan time.Time, 1)
+	if d <= 0 {
+		ch <- c.now
+		return ch
+	}
+	c.waiters = append(c.waiters, fakeWaiter{at: c.now.Add(d), ch: ch})
+	return ch
+}
+
+// moves the clock forward, delivering time to waiters that are due
+func (c *fakeClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+	waiting := c.waiters[:0]
+	for _, w := range c.waiters {
+		if w.at.After(c.now) {
+			waiting = append(waiting, w)
+			continue
+		}
+		w.ch <- c.now
+	}
+	c.waiters = waiting
+}
+
+// returns whether the channel has a value without blocking
+func ready(ch <-chan time.Time) bool {
+	select {
+	case <-ch:
+		return true
+	default:
+		return false
+	}
+}
+
+func TestFakeClockAfter(t *testing.T) {
+	c := newFakeClock()
+	ch := c.After(time.Second)
+	c.Advance(999 * time.Millisecond)
+	if ready(ch) {
+		t.Fatal("fired before the duration passed")
+	}
+	c.Advance(time.Millisecond)
+	if !ready(ch) {
+		t.Fatal("did not fire after the duration passed")
+	}
+}
+
+func TestTickFollowsClock(t *testing.T) {
+	c := newFakeClock()
+	a := testState(t, "/music/a.mp3")
+	a.tracksQueue.clock = c
+	a.tracksQueue.stopped = false
+	start := c.Now()
+
+	for range 5 {
+		cmd := a.tick()
+		c.Advance(tickInterval)
+		m, _ := a.Update(cmd())
+		a = m.(appState)
+	}
+
+	if a.frame != 5 {
+		t.Errorf("frame = %d, want 5", a.frame)
+	}
+	// the first tick only starts measuring
+	if want := 4 * tickInterval; a.tracksQueue.stats.listened != want {
+		t.Errorf("listened = %v, want %v", a.tracksQueue.stats.listened, want)
+	}
+	if got := a.tracksQueue.stats.lastTick; !got.Equal(start.Add(5 * tickInterval)) {
+		t.Errorf("last tick at %v, want %v", got, start.Add(5*tickInterval))
+	}
+}
+
+func TestSkipWaitsForDebounce(t *testing.T) {
+	c := newFakeClock()
+	a := testState(t, "/music/a.mp3", "/music/b.mp3", "/music/c.mp3")
+	a.tracksQueue.clock = c
+
+	a, first := a.skipTracks(1)
+	a, second := a.skipTracks(1)
+	done := make(chan any, 2)
+	go func() { done <- first() }()
+	go func() { done <- second() }()
+
+	c.Advance(trackChangeDebounce - time.Millisecond)
+	select {
+	case <-done:
+		t.Fatal("skip applied before the debounce passed")
+	case <-time.After(10 * time.Millisecond):
+	}
+
+	c.Advance(time.Millisecond)
+	for range 2 {
+		m, _ := a.Update(<-done)
+		a = m.(appState)
+	}
+	if a.tracksQueue.currentTrack != 2 {
+		t.Errorf("current track = %d, want 2", a.tracksQueue.currentTrack)
+	}
+}
+
+func TestEventTimeFromClock(t *testing.T) {
+	c := newFakeClock()
+	var buf bytes.Buffer
+	l := newEventLog(&buf, c)
+
+	l.trackEvent(eventTrackStarted, "/music/a.mp3")
+	c.Advance(time.Minute)
+	l.volumeChanged(80)
+
+	dec := json.NewDecoder(&buf)
+	for _, want := range []time.Time{c.Now().Add(-time.Minute), c.Now()} {
+		var e event
+		if err := dec.Decode(&e); err != nil {
+			t.Fatal(err)
+		}
+		if !e.Time.Equal(want) {
+			t.Errorf("%s event at %v, want %v", e.Type, e.Time, want)
+		}
+	}
+}
@@ -0,0 +1,228 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// what the text typed at the bottom line is used for
+type inputMode int
+
+const (
+	inputNone inputMode = iota
+	// vim-style command line opened with ':'
+	inputCommand
+	// new name of the file under cursor
+	inputRename
+	// search in the help
+	inputHelpFilter
+	// directory the browser jumps to
+	inputGoToPath
+	// files and directories to queue, usually pasted by dropping them into the terminal
+	inputAddPaths
+)
+
+func (m inputMode) prompt() string {
+	switch m {
+	case inputCommand:
+		return ":"
+	case inputRename:
+		return "rename to: "
+	case inputHelpFilter:
+		return "/"
+	case inputGoToPath:
+		return "go to: "
+	case inputAddPaths:
+		return "add: "
+	}
+	return ""
+}
+
+// edits text input. enter submits the text, esc cancels
+func (a appState) updateInput(msg tea.KeyMsg) appState {
+	switch msg.Type {
+	case tea.KeyEsc, tea.KeyCtrlC:
+		a.inputMode = inputNone
+		a.input = ""
+	case tea.KeyEnter:
+		mode, input := a.inputMode, a.input
+		a.inputMode = inputNone
+		a.input = ""
+		a = a.submitInput(mode, input)
+	case tea.KeyBackspace:
+		runes := []rune(a.input)
+		if len(runes) != 0 {
+			a.input = string(runes[:len(runes)-1])
+		}
+	case tea.KeySpace:
+		a.input += " "
+	case tea.KeyRunes:
+		a.input += string(msg.Runes)
+	}
+	return a
+}
+
+func (a appState) submitInput(mode inputMode, input string) appState {
+	switch mode {
+	case inputCommand:
+		return a.runCommand(input)
+	case inputRename:
+		return a.renameFile(a.renaming, input)
+	case inputHelpFilter:
+		a.keysFilter = input
+	case inputGoToPath:
+		return a.goToPath(input)
+	case inputAddPaths:
+		return a.addPastedPaths(input)
+	}
+	return a
+}
+
+// resolves path typed by user relative to the current directory
+func (a appState) resolvePath(path string) (string, error) {
+	if !strings.HasPrefix(path, "~") && !filepath.IsAbs(path) {
+		path = filepath.Join(a.currentDir, path)
+	}
+	return absPath(path)
+}
+
+// opens directory typed by user in the browser
+func (a appState) goToPath(input string) appState {
+	input = strings.TrimSpace(input)
+	if input == "" {
+		return a
+	}
+	path, err := a.resolvePath(input)
+	if err != nil {
+		a.status = err.Error()
+		return a
+	}
+	browsable, err := isBrowsable(path)
+	if err != nil {
+		a.status = err.Error()
+		return a
+	}
+	if !browsable {
+		a.status = "not a directory: " + path
+		return a
+	}
+	next := a
+	next.currentDir = path
+	next.cursor = 0
+	return a.navigate(next.updateChoices())
+}
+
+// queues playlist, directory or track. returns status describing the result
+func (s *tracksQueue) addPath(path string) (string, error) {
+	browsable, err := isBrowsable(path)
+	if err != nil {
+		return "", err
+	}
+	if isPlaylist(path) {
+		summary, err := s.addPlaylist(path)
+		if err != nil {
+			return "", err
+		}
+		return summary.String(), nil
+	}
+	if browsable {
+		summary, err := s.addDir(path, true)
+		if err != nil {
+			return "", err
+		}
+		return summary.String(), nil
+	}
+	if s.hasTrack(path) {
+		return "already queued", nil
+	}
+	track, err := loadTrack(path)
+	if err != nil {
+		return "", err
+	}
+	s.addTrack(track)
+	return "queued " + filepath.Base(path), nil
+}
+
+// executes command typed in command mode
+func (a appState) runCommand(input string) appState {
+	name, arg, _ := strings.Cut(strings.TrimSpace(input), " ")
+	arg = strings.TrimSpace(arg)
+	switch name {
+	case "":
+		return a
+	case "add":
+		if arg == "" {
+			a.status = "usage: add PATH"
+			return a
+		}
+		path, err := a.resolvePath(arg)
+		if err != nil {
+			a.status = err.Error()
+			return a
+		}
+		a.status, err = a.tracksQueue.addPath(path)
+		if err != nil {
+			a.status = err.Error()
+			return a
+		}
+		a.tracksQueue.play()
+	case "goto":
+		position, err := strconv.Atoi(arg)
+		if err != nil || position < 1 || position > a.tracksQueue.len() {
+			a.status = fmt.Sprintf("usage: goto N, where N is from 1 to %d", a.tracksQueue.len())
+			return a
+		}
+		a.tracksQueue.goToTrack(position - 1)
+	case "volume":
+		volume, err := strconv.Atoi(strings.TrimSuffix(arg, "%"))
+		if err != nil {
+			a.status = fmt.Sprintf("usage: volume PERCENTS, from %d to %d", minVolume, maxVolume)
+			return a
+		}
+		a.tracksQueue.setVolumePercent(volume)
+	case "save":
+		if arg == "" {
+			a.status = "usage: save FILE.m3u"
+			return a
+		}
+		path, err := a.resolvePath(arg)
+		if err != nil {
+			a.status = err.Error()
+			return a
+		}
+		if err := writeM3U(path, a.tracksQueue.getTracks()); err != nil {
+			a.status = err.Error()
+			return a
+		}
+		a = a.navigate(a.updateChoices())
+		a.status = "saved " + path
+	case "export":
+		if arg == "" {
+			a.status = "usage: export FILE.m3u8"
+			return a
+		}
+		path, err := a.resolvePath(arg)
+		if err != nil {
+			a.status = err.Error()
+			return a
+		}
+		if err := a.tracksQueue.exportExtendedM3U(path); err != nil {
+			a.status = err.Error()
+			return a
+		}
+		a = a.navigate(a.updateChoices())
+		a.status = "exported " + path
+	case "preset":
+		return a.presetCommand(arg)
+	case "keys":
+		a.showHelp = true
+		a.keysFilter = arg
+	default:
+		a.status = "unknown command: " + name
+	}
+	return a
+}
@@ -0,0 +1,127 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// returns path to the config file with given name.
+// respects $XDG_CONFIG_HOME and falls back to ~/.config
+func configPath(name string) string {
+	return xdgPath("XDG_CONFIG_HOME", ".config", name)
+}
+
+// returns path to the state file with given name.
+// respects $XDG_STATE_HOME and falls back to ~/.local/state
+func statePath(name string) string {
+	return xdgPath("XDG_STATE_HOME", filepath.Join(".local", "state"), name)
+}
+
+func xdgPath(envVar string, homeFallback string, name string) string {
+	baseDir := os.Getenv(envVar)
+	// relative paths are invalid according to the XDG spec and must be ignored
+	if !filepath.IsAbs(baseDir) {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return name
+		}
+		baseDir = filepath.Join(home, homeFallback)
+	}
+	return filepath.Join(baseDir, executableName, name)
+}
+
+// settings kept between runs
+type config struct {
+	// start the next track when the current one ends
+	AutoAdvance bool `json:"autoAdvance"`
+	// move cursor to the next entry after adding track to queue
+	AdvanceCursorOnQueue bool `json:"advanceCursorOnQueue"`
+	// show the most used keys below the browser instead of the short footer
+	Keybar bool `json:"keybar"`
+	// what to do with playlist of the entered directory when queue is empty
+	PlaylistAutoload string `json:"playlistAutoload"`
+	// moving cursor past the last entry goes to the first one and vice versa
+	WrapCursor bool `json:"wrapCursor"`
+	// show number of queued tracks in the header while browsing
+	QueueBadge bool `json:"queueBadge"`
+	// adding tracks to the full queue removes the oldest played ones. 0 disables the limit
+	MaxQueue int `json:"maxQueue"`
+	// directory browsing starts in when no directory is given
+	MusicDir string `json:"musicDir"`
+	// whether adding a directory appends its tracks or replaces the queue with them
+	FolderEnqueue string `json:"folderEnqueue"`
+	// name of the color theme
+	Theme string `json:"theme"`
+	// colors of the UI parts replacing the ones of the theme
+	Colors map[string]string `json:"colors"`
+	// named playback settings applied with the preset command
+	Presets map[string]preset `json:"presets,omitempty"`
+}
+
+const (
+	playlistAutoloadAsk  = "ask"
+	playlistAutoloadAuto = "auto"
+	playlistAutoloadOff  = "off"
+)
+
+const (
+	folderEnqueueAppend  = "append"
+	folderEnqueueReplace = "replace"
+)
+
+func defaultConfig() config {
+	return config{
+		AutoAdvance:          true,
+		AdvanceCursorOnQueue: true,
+		Keybar:               true,
+		PlaylistAutoload:     playlistAutoloadAsk,
+		FolderEnqueue:        folderEnqueueAppend,
+		Theme:                defaultThemeName,
+	}
+}
+
+// reads config file. missing file or settings are set to defaults
+func loadConfig() (config, error) {
+	c := defaultConfig()
+	data, err := os.ReadFile(configPath("config.json"))
+	if errors.Is(err, os.ErrNotExist) {
+		return c, nil
+	}
+	if err != nil {
+		return c, err
+	}
+	if err := json.Unmarshal(data, &c); err != nil {
+		return c, fmt.Errorf("%s: %w", configPath("config.json"), err)
+	}
+	switch c.PlaylistAutoload {
+	case playlistAutoloadAsk, playlistAutoloadAuto, playlistAutoloadOff:
+	default:
+		return c, fmt.Errorf("%s: playlistAutoload must be %q, %q or %q", configPath("config.json"),
+			playlistAutoloadAsk, playlistAutoloadAuto, playlistAutoloadOff)
+	}
+	switch c.FolderEnqueue {
+	case folderEnqueueAppend, folderEnqueueReplace:
+	default:
+		return c, fmt.Errorf("%s: folderEnqueue must be %q or %q", configPath("config.json"),
+			folderEnqueueAppend, folderEnqueueReplace)
+	}
+	if _, err := buildTheme(c.Theme, c.Colors); err != nil {
+		return c, fmt.Errorf("%s: %w", configPath("config.json"), err)
+	}
+	return c, nil
+}
+
+func (c config) save() error {
+	path := configPath("config.json")
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0644)
+}
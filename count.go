@@ -0,0 +1,57 @@
+package main
+
+import (
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// digits typed right before a command are its count, like in vim. if no
+// command follows, the last digit seeks once typing stops
+const countTimeout = 300 * time.Millisecond
+
+type countTimeoutMsg struct {
+	id int
+}
+
+// returns whether the key continues or takes the pending count
+func isCountKey(key string) bool {
+	return len(key) == 1 && key[0] >= '0' && key[0] <= '9' || key == "N"
+}
+
+func (a appState) typeCount(digit int) (appState, tea.Cmd) {
+	a.count = a.count*10 + digit
+	a.countID++
+	id := a.countID
+	return a, clockTick(a.tracksQueue.clock, countTimeout, func(time.Time) tea.Msg {
+		return countTimeoutMsg{id: id}
+	})
+}
+
+// returns the pending count, or defaultCount if none is typed, and forgets it
+func (a appState) takeCount(defaultCount int) (appState, int) {
+	count := a.count
+	a.count = 0
+	if count == 0 {
+		return a, defaultCount
+	}
+	return a, count
+}
+
+// seeks by the last digit of the count that no command took
+func (a appState) expireCount() appState {
+	if a.count == 0 {
+		return a
+	}
+	digit := a.count % 10
+	a.count = 0
+	return a.seekDigit(digit)
+}
+
+// seeks to digit tens of percent of the current track
+func (a appState) seekDigit(digit int) appState {
+	if err := a.tracksQueue.seekPercent(float64(digit) * 10); err != nil {
+		a.status = err.Error()
+	}
+	return a
+}
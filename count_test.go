@@ -0,0 +1,136 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// state browsing a directory of short tracks, a cover, and a subdirectory
+func countState(t *testing.T) appState {
+	t.Helper()
+	a := testState(t)
+	ogg, err := os.ReadFile("testdata/short.ogg")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"a.ogg", "b.ogg", "c.ogg", "d.ogg"} {
+		if err := os.WriteFile(filepath.Join(a.currentDir, name), ogg, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.WriteFile(filepath.Join(a.currentDir, "cover.jpg"), nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Mkdir(filepath.Join(a.currentDir, "sub"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	a, err = a.updateChoices()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(a.tracksQueue.clear)
+	return a
+}
+
+func queuedNames(q tracksQueue) []string {
+	names := make([]string, 0, q.len())
+	for _, path := range queuePaths(q) {
+		names = append(names, filepath.Base(path))
+	}
+	return names
+}
+
+func TestQueueFromCursorCount(t *testing.T) {
+	tests := []struct {
+		keys       []string
+		wantQueued []string
+		wantStatus string
+	}{
+		{[]string{"N"}, []string{"a.ogg", "b.ogg"}, "queued 2, skipped 0 (unsupported)"},
+		{[]string{"2", "N"}, []string{"a.ogg", "b.ogg", "c.ogg"}, "queued 3, skipped 0 (unsupported)"},
+		{[]string{"3", "N"}, []string{"a.ogg", "b.ogg", "c.ogg", "d.ogg"}, "queued 4, skipped 1 (unsupported)"},
+		{[]string{"1", "2", "N"}, []string{"a.ogg", "b.ogg", "c.ogg", "d.ogg"}, "queued 4, skipped 1 (unsupported)"},
+	}
+	for _, test := range tests {
+		t.Run(strings.Join(test.keys, ""), func(t *testing.T) {
+			a := countState(t)
+			for _, key := range test.keys {
+				a = press(a, key)
+			}
+			if got := queuedNames(a.tracksQueue); !slices.Equal(got, test.wantQueued) {
+				t.Errorf("queued %v, want %v", got, test.wantQueued)
+			}
+			if a.status != test.wantStatus {
+				t.Errorf("status = %q, want %q", a.status, test.wantStatus)
+			}
+			if a.count != 0 {
+				t.Errorf("count %d left after N", a.count)
+			}
+		})
+	}
+}
+
+func TestQueueFromCursorPassesQueuedOver(t *testing.T) {
+	a := countState(t)
+	a.cursor = 1
+	a = press(a, " ")
+	a.cursor = 0
+	a = press(press(a, "2"), "N")
+	if got, want := queuedNames(a.tracksQueue), []string{"b.ogg", "a.ogg", "c.ogg", "d.ogg"}; !slices.Equal(got, want) {
+		t.Errorf("queued %v, want %v", got, want)
+	}
+}
+
+func TestDigitSeeksWhenCountExpires(t *testing.T) {
+	a := testState(t, "/music/a.mp3")
+	stream := a.tracksQueue.queue[0].stream.(*fakeStream)
+
+	a = press(a, "5")
+	if stream.pos != 0 {
+		t.Fatalf("seeked to %d while count may continue", stream.pos)
+	}
+	// timeout of an earlier digit does not cut the count short
+	m, _ := a.Update(countTimeoutMsg{id: a.countID - 1})
+	a = m.(appState)
+	if stream.pos != 0 || a.count != 5 {
+		t.Fatalf("stale timeout applied, position %d, count %d", stream.pos, a.count)
+	}
+	m, _ = a.Update(countTimeoutMsg{id: a.countID})
+	a = m.(appState)
+	if want := stream.length / 2; stream.pos != want {
+		t.Errorf("position = %d, want %d", stream.pos, want)
+	}
+	if a.count != 0 {
+		t.Errorf("count %d left after timeout", a.count)
+	}
+}
+
+func TestDigitSeeksBeforeOtherKey(t *testing.T) {
+	a := testState(t, "/music/a.mp3")
+	stream := a.tracksQueue.queue[0].stream.(*fakeStream)
+
+	a = press(press(a, "3"), "j")
+	if want := stream.length * 3 / 10; stream.pos != want {
+		t.Errorf("position = %d, want %d", stream.pos, want)
+	}
+	if a.count != 0 {
+		t.Errorf("count %d left after other key", a.count)
+	}
+}
+
+func TestZeroSeeksToStart(t *testing.T) {
+	a := testState(t, "/music/a.mp3")
+	stream := a.tracksQueue.queue[0].stream.(*fakeStream)
+	stream.pos = stream.length / 2
+
+	a = press(a, "0")
+	if stream.pos != 0 {
+		t.Errorf("position = %d, want 0", stream.pos)
+	}
+	if a.count != 0 {
+		t.Errorf("0 started count %d", a.count)
+	}
+}
@@ -0,0 +1,150 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+var errBadCueSheet = errors.New("bad cue sheet")
+
+// track of the album inside one big file described by a cue sheet
+type cueTrack struct {
+	title string
+	// first sample of the track in the file stream
+	start int
+}
+
+type cueEntry struct {
+	title string
+	start time.Duration
+}
+
+// loads file referenced by the cue sheet. only the first FILE of the sheet is used
+func loadCueSheet(cuePath string) (track, error) {
+	f, err := os.Open(cuePath)
+	if err != nil {
+		return track{}, err
+	}
+	defer f.Close()
+	audioFile := ""
+	files := 0
+	entries := []cueEntry{}
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		keyword, value, _ := strings.Cut(line, " ")
+		// tracks of other files are ignored
+		if keyword == "FILE" {
+			files++
+		}
+		if files != 1 {
+			continue
+		}
+		switch keyword {
+		case "FILE":
+			// last word is the file type
+			audioFile = unquoteCue(value[:max(strings.LastIndex(value, " "), 0)])
+		case "TRACK":
+			entries = append(entries, cueEntry{start: -1})
+		case "TITLE":
+			if len(entries) != 0 {
+				entries[len(entries)-1].title = unquoteCue(value)
+			}
+		case "INDEX":
+			number, timestamp, _ := strings.Cut(value, " ")
+			if len(entries) == 0 || number != "01" {
+				break
+			}
+			start, err := parseCueTime(timestamp)
+			if err != nil {
+				return track{}, err
+			}
+			entries[len(entries)-1].start = start
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return track{}, err
+	}
+	if audioFile == "" || len(entries) == 0 {
+		return track{}, errBadCueSheet
+	}
+	audioPath := filepath.Join(filepath.Dir(cuePath), audioFile)
+	// sheet referencing a sheet would load sheets forever
+	if fileFormat(audioPath) == "cue" {
+		return track{}, fmt.Errorf("%s: FILE is a cue sheet: %w", filepath.Base(cuePath), errBadCueSheet)
+	}
+	t, err := loadTrack(audioPath)
+	if err != nil {
+		return track{}, err
+	}
+	t.path = cuePath
+	// offset is saved under the path of the sheet, not of its audio file
+	if err := t.applyStartOffset(); err != nil {
+		t.close()
+		return track{}, err
+	}
+	t.rebuildPlayback()
+	for i, entry := range entries {
+		if entry.start < 0 {
+			continue
+		}
+		title := entry.title
+		if title == "" {
+			title = fmt.Sprintf("track %d", i+1)
+		}
+		t.cues = append(t.cues, cueTrack{title: title, start: t.format.SampleRate.N(entry.start)})
+	}
+	return t, nil
+}
+
+func unquoteCue(value string) string {
+	return strings.Trim(strings.TrimSpace(value), `"`)
+}
+
+// parses mm:ss:ff time, where ff are frames (1/75 of a second)
+func parseCueTime(timestamp string) (time.Duration, error) {
+	var minutes, seconds, frames int
+	if _, err := fmt.Sscanf(timestamp, "%d:%d:%d", &minutes, &seconds, &frames); err != nil {
+		return 0, errBadCueSheet
+	}
+	return time.Duration(minutes)*time.Minute +
+		time.Duration(seconds)*time.Second +
+		time.Duration(frames)*time.Second/75, nil
+}
+
+// returns index of the cue playing at position or -1 if track has no cues
+func (t track) cueAt(pos int) int {
+	index := -1
+	for i, cue := range t.cues {
+		if cue.start <= pos {
+			index = i
+		}
+	}
+	return index
+}
+
+// seeks to the cue of the current track with given offset from the playing one.
+// returns false if there is no such cue
+func (s *tracksQueue) seekCue(delta int) bool {
+	currentTrack, ok := s.getCurrentTrack()
+	if !ok || len(currentTrack.cues) == 0 || !currentTrack.seekable() {
+		return false
+	}
+	pos, _, _ := s.position()
+	index := currentTrack.cueAt(pos) + delta
+	if index < 0 || index >= len(currentTrack.cues) {
+		return false
+	}
+	start := currentTrack.cues[index].start
+	// cues in the trimmed silence at the end are never played
+	if currentTrack.trimEnd != 0 && start >= currentTrack.trimEnd {
+		return false
+	}
+	// seek keeps playback inside the trimmed part of the track
+	return s.seek(start) == nil
+}
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// number of samples the streamer plays before it ends
+func streamedSamples(s interface {
+	Stream([][2]float64) (int, bool)
+}) int {
+	total := 0
+	buf := make([][2]float64, 512)
+	for {
+		n, ok := s.Stream(buf)
+		total += n
+		if !ok {
+			return total
+		}
+	}
+}
+
+// track with silence trimmed from both ends, split by cues every 3000 samples
+func trimmedCueState(t *testing.T) appState {
+	t.Helper()
+	a := testState(t)
+	tr := testTrack("/music/album.mp3", 10000)
+	tr.trimStart, tr.trimEnd = 1000, 8000
+	tr.stream.Seek(tr.trimStart)
+	tr.resampled = tr.playback()
+	tr.cues = []cueTrack{{"one", 0}, {"two", 3000}, {"three", 6000}, {"four", 9000}}
+	a.tracksQueue.addTrack(tr)
+	return a
+}
+
+func TestSeekCueInsideTrimmedTrack(t *testing.T) {
+	a := trimmedCueState(t)
+	q := &a.tracksQueue
+
+	if !q.seekCue(1) {
+		t.Fatal("no cue after the first one")
+	}
+	if pos, _, _ := q.position(); pos != 3000 {
+		t.Fatalf("position = %d, want 3000", pos)
+	}
+	// playback still stops at the trimmed end
+	if n := streamedSamples(q.queue[0].resampled); n != 5000 {
+		t.Errorf("played %d samples after the cue, want 5000", n)
+	}
+}
+
+func TestSeekCueBeforeTrimmedStart(t *testing.T) {
+	a := trimmedCueState(t)
+	q := &a.tracksQueue
+	q.seekCue(1)
+
+	if !q.seekCue(-1) {
+		t.Fatal("no cue before the second one")
+	}
+	if pos, _, _ := q.position(); pos != 1000 {
+		t.Errorf("position = %d, want trimmed start 1000", pos)
+	}
+}
+
+func TestSeekCueInTrimmedEnd(t *testing.T) {
+	a := trimmedCueState(t)
+	q := &a.tracksQueue
+	q.seek(6500)
+
+	if q.seekCue(1) {
+		t.Error("sought to a cue in the trimmed silence")
+	}
+	if pos, _, _ := q.position(); pos != 6500 {
+		t.Errorf("position = %d, want 6500", pos)
+	}
+}
+
+func TestCueSheetReferencingCueSheet(t *testing.T) {
+	dir := t.TempDir()
+	cuePath := filepath.Join(dir, "album.cue")
+	sheet := "FILE \"album.cue\" WAVE\nTRACK 01 AUDIO\nINDEX 01 00:00:00\n"
+	if err := os.WriteFile(cuePath, []byte(sheet), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := loadTrack(cuePath); !errors.Is(err, errBadCueSheet) {
+		t.Fatalf("loading sheet of itself returned %v, want %v", err, errBadCueSheet)
+	}
+}
+
+func TestCueTrackUsesItsAudioFile(t *testing.T) {
+	dir := t.TempDir()
+	audio, err := os.ReadFile(filepath.Join("testdata", "short.ogg"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "album.ogg"), audio, 0644); err != nil {
+		t.Fatal(err)
+	}
+	cuePath := filepath.Join(dir, "album.cue")
+	sheet := "FILE \"album.ogg\" WAVE\nTRACK 01 AUDIO\nINDEX 01 00:00:00\n"
+	if err := os.WriteFile(cuePath, []byte(sheet), 0644); err != nil {
+		t.Fatal(err)
+	}
+	tr, err := loadTrack(cuePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tr.close()
+
+	if info := tr.info(); !strings.Contains(info, "Ogg Vorbis") {
+		t.Errorf("info %q has no codec of the audio file", info)
+	}
+	msg := scanPeakCmd(tr)().(peakScannedMsg)
+	if msg.err != nil || msg.path != cuePath {
+		t.Errorf("peak scan of %s failed: %v", msg.path, msg.err)
+	}
+}
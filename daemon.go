@@ -0,0 +1,81 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"time"
+)
+
+// set in the environment of the background process started by startDaemon
+const daemonEnv = "GOMUSIC_DAEMON"
+
+// how long the starting process waits for the background one to answer,
+// and how often it asks
+const (
+	daemonStartTimeout  = 10 * time.Second
+	daemonCheckInterval = 100 * time.Millisecond
+)
+
+var errDaemonExited = errors.New("background process exited before it started playing")
+
+// reports whether this process is the background one
+func isDaemon() bool {
+	return os.Getenv(daemonEnv) != ""
+}
+
+// starts this program again with the same arguments, detached from the terminal.
+// returns pid of the started process once it is ready
+func startDaemon() (int, error) {
+	executable, err := os.Executable()
+	if err != nil {
+		return 0, err
+	}
+	cmd := exec.Command(executable, os.Args[1:]...)
+	cmd.Env = append(os.Environ(), daemonEnv+"=1")
+	// nil streams are connected to the null device
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = nil, nil, nil
+	cmd.SysProcAttr = detachedProcAttr()
+	if err := cmd.Start(); err != nil {
+		return 0, err
+	}
+	exited := make(chan error, 1)
+	go func() {
+		exited <- cmd.Wait()
+	}()
+	pid := cmd.Process.Pid
+	return pid, waitDaemon(pid, exited)
+}
+
+// waits until the background process answers on the remote socket. when another
+// instance holds the socket, the process is trusted if it still runs after the timeout
+func waitDaemon(pid int, exited <-chan error) error {
+	deadline := time.After(daemonStartTimeout)
+	for {
+		select {
+		case err := <-exited:
+			if err != nil {
+				return fmt.Errorf("%w: %v", errDaemonExited, err)
+			}
+			return errDaemonExited
+		case <-deadline:
+			return nil
+		case <-time.After(daemonCheckInterval):
+		}
+		if daemonAnswers(pid) {
+			return nil
+		}
+	}
+}
+
+// reports whether the process with the pid answers remote commands
+func daemonAnswers(pid int) bool {
+	answer, err := remoteCommand("status")
+	if err != nil {
+		return false
+	}
+	var status playerStatus
+	return json.Unmarshal([]byte(answer), &status) == nil && status.Pid == pid
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"net"
+	"testing"
+	"time"
+)
+
+// answers status requests on the remote socket as the process with the pid
+func fakeInstance(t *testing.T, pid int) {
+	t.Helper()
+	t.Setenv("XDG_RUNTIME_DIR", t.TempDir())
+	listener, err := net.Listen("unix", socketPath())
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { listener.Close() })
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			bufio.NewReader(conn).ReadString('\n')
+			json.NewEncoder(conn).Encode(playerStatus{Pid: pid})
+			conn.Close()
+		}
+	}()
+}
+
+func TestWaitDaemonExited(t *testing.T) {
+	t.Setenv("XDG_RUNTIME_DIR", t.TempDir())
+	exited := make(chan error, 1)
+	exited <- errors.New("exit status 1")
+	if err := waitDaemon(4242, exited); !errors.Is(err, errDaemonExited) {
+		t.Errorf("err = %v, want %v", err, errDaemonExited)
+	}
+}
+
+func TestWaitDaemonReady(t *testing.T) {
+	fakeInstance(t, 4242)
+	start := time.Now()
+	if err := waitDaemon(4242, make(chan error)); err != nil {
+		t.Fatal(err)
+	}
+	if elapsed := time.Since(start); elapsed >= daemonStartTimeout {
+		t.Errorf("ready process waited for the timeout, %v", elapsed)
+	}
+}
+
+func TestWaitDaemonIgnoresOtherInstance(t *testing.T) {
+	fakeInstance(t, 1)
+	exited := make(chan error, 1)
+	go func() {
+		time.Sleep(5 * daemonCheckInterval)
+		exited <- errors.New("exit status 1")
+	}()
+	if err := waitDaemon(4242, exited); !errors.Is(err, errDaemonExited) {
+		t.Errorf("err = %v, want %v", err, errDaemonExited)
+	}
+}
@@ -0,0 +1,13 @@
+package main
+
+import "syscall"
+
+// DETACHED_PROCESS, the process gets no console
+const detachedProcess = 0x00000008
+
+func detachedProcAttr() *syscall.SysProcAttr {
+	return &syscall.SysProcAttr{
+		CreationFlags: detachedProcess | syscall.CREATE_NEW_PROCESS_GROUP,
+		HideWindow:    true,
+	}
+}
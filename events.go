@@ -0,0 +1,65 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"log"
+	"sync"
+	"time"
+)
+
+// kinds of events written to the event log
+const (
+	eventTrackLoaded   = "track_loaded"
+	eventTrackStarted  = "track_started"
+	eventTrackEnded    = "track_ended"
+	eventVolumeChanged = "volume_changed"
+	eventError         = "error"
+)
+
+// machine readable record of something that happened in the player
+type event struct {
+	Time   time.Time `json:"time"`
+	Type   string    `json:"type"`
+	Path   string    `json:"path,omitempty"`
+	Volume *int      `json:"volume,omitempty"`
+	Error  string    `json:"error,omitempty"`
+}
+
+// writes events as JSON lines. nil log discards events
+type eventLog struct {
+	mu    sync.Mutex
+	enc   *json.Encoder
+	clock clock
+}
+
+// events of the session, enabled by --events flag
+var events *eventLog
+
+func newEventLog(w io.Writer, c clock) *eventLog {
+	return &eventLog{enc: json.NewEncoder(w), clock: c}
+}
+
+func (l *eventLog) emit(e event) {
+	if l == nil {
+		return
+	}
+	e.Time = l.clock.Now()
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if err := l.enc.Encode(e); err != nil {
+		log.Printf("failed to write event: %v", err)
+	}
+}
+
+func (l *eventLog) trackEvent(eventType string, path string) {
+	l.emit(event{Type: eventType, Path: path})
+}
+
+func (l *eventLog) volumeChanged(volume int) {
+	l.emit(event{Type: eventVolumeChanged, Volume: &volume})
+}
+
+func (l *eventLog) error(path string, err error) {
+	l.emit(event{Type: eventError, Path: path, Error: err.Error()})
+}
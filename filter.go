@@ -0,0 +1,103 @@
+package main
+
+import (
+	"math"
+
+	"github.com/gopxl/beep/v2"
+)
+
+// preset of the loudness filter
+const (
+	loudnessFrequency = 100
+	loudnessGain      = 6
+)
+
+// switchable low-shelf biquad filter. boosts or cuts frequencies below
+// the cutoff. coefficients are taken from the Audio EQ Cookbook by R. Bristow-Johnson
+type lowShelf struct {
+	Streamer beep.Streamer
+	// filter passes samples untouched when disabled
+	Enabled bool
+
+	b0, b1, b2, a1, a2 float64
+	// previous inputs and outputs for each channel
+	x1, x2, y1, y2 [2]float64
+}
+
+func newLowShelf(streamer beep.Streamer, sampleRate beep.SampleRate, frequency float64, gainDb float64) *lowShelf {
+	a := math.Pow(10, gainDb/40)
+	w0 := 2 * math.Pi * frequency / float64(sampleRate)
+	cos := math.Cos(w0)
+	// shelf slope 1 is the steepest one without overshoot
+	alpha := math.Sin(w0) / 2 * math.Sqrt2
+	sqrtA := 2 * math.Sqrt(a) * alpha
+
+	a0 := (a + 1) + (a-1)*cos + sqrtA
+	return &lowShelf{
+		Streamer: streamer,
+		b0:       a * ((a + 1) - (a-1)*cos + sqrtA) / a0,
+		b1:       2 * a * ((a - 1) - (a+1)*cos) / a0,
+		b2:       a * ((a + 1) - (a-1)*cos - sqrtA) / a0,
+		a1:       -2 * ((a - 1) + (a+1)*cos) / a0,
+		a2:       ((a + 1) + (a-1)*cos - sqrtA) / a0,
+	}
+}
+
+func (f *lowShelf) Stream(samples [][2]float64) (int, bool) {
+	n, ok := f.Streamer.Stream(samples)
+	if !f.Enabled {
+		return n, ok
+	}
+	for i := range samples[:n] {
+		for c := 0; c < 2; c++ {
+			x := samples[i][c]
+			y := f.b0*x + f.b1*f.x1[c] + f.b2*f.x2[c] - f.a1*f.y1[c] - f.a2*f.y2[c]
+			f.x2[c], f.x1[c] = f.x1[c], x
+			f.y2[c], f.y1[c] = f.y1[c], y
+			samples[i][c] = y
+		}
+	}
+	return n, ok
+}
+
+func (f *lowShelf) Err() error {
+	return f.Streamer.Err()
+}
+
+// smoothly changes gain of the stream to 1
+type gainRamp struct {
+	Streamer beep.Streamer
+	gain     float64
+	// change of gain per sample
+	step float64
+}
+
+func newGainRamp(streamer beep.Streamer) *gainRamp {
+	return &gainRamp{Streamer: streamer, gain: 1}
+}
+
+// starts ramp from the gain, reaching 1 after given number of samples
+func (r *gainRamp) start(from float64, samples int) {
+	r.gain = from
+	r.step = (1 - from) / float64(max(samples, 1))
+}
+
+func (r *gainRamp) Stream(samples [][2]float64) (int, bool) {
+	n, ok := r.Streamer.Stream(samples)
+	for i := range samples[:n] {
+		if r.gain == 1 {
+			break
+		}
+		samples[i][0] *= r.gain
+		samples[i][1] *= r.gain
+		r.gain += r.step
+		if (r.step > 0 && r.gain > 1) || (r.step < 0 && r.gain < 1) {
+			r.gain = 1
+		}
+	}
+	return n, ok
+}
+
+func (r *gainRamp) Err() error {
+	return r.Streamer.Err()
+}
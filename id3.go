@@ -0,0 +1,123 @@
+package main
+
+import (
+	"encoding/binary"
+	"io"
+	"strings"
+	"unicode/utf16"
+)
+
+// frames of ID3v2 tag
+type id3Tag struct {
+	version byte
+	frames  []id3Frame
+}
+
+// reads ID3v2 tag from the start of the file.
+// returns empty tag if there is none
+func readID3(r io.Reader) (id3Tag, error) {
+	header := make([]byte, 10)
+	if _, err := io.ReadFull(r, header); err != nil || string(header[:3]) != "ID3" {
+		return id3Tag{}, nil
+	}
+	version, flags := header[3], header[5]
+	// v2.2 uses other frame ids, unsynchronised tags are rare and not worth decoding
+	if version < 3 || flags&0x80 != 0 {
+		return id3Tag{}, nil
+	}
+	data := make([]byte, syncsafe(header[6:10]))
+	if _, err := io.ReadFull(r, data); err != nil {
+		return id3Tag{}, err
+	}
+	if flags&0x40 != 0 && len(data) >= 4 {
+		// v2.3 extended header size doesn't include the size itself
+		size := int(binary.BigEndian.Uint32(data))
+		if version == 3 {
+			size += 4
+		} else {
+			size = syncsafe(data[:4])
+		}
+		data = data[min(size, len(data)):]
+	}
+	return id3Tag{version: version, frames: id3Frames(data, version)}, nil
+}
+
+// returns text of the first frame with given id
+func (t id3Tag) text(id string) string {
+	for _, frame := range t.frames {
+		if frame.id == id {
+			return decodeID3Text(frame.data)
+		}
+	}
+	return ""
+}
+
+// 7 bits of every byte are used, so the value never looks like mp3 sync
+func syncsafe(b []byte) int {
+	return int(b[0])<<21 | int(b[1])<<14 | int(b[2])<<7 | int(b[3])
+}
+
+type id3Frame struct {
+	id   string
+	data []byte
+}
+
+// splits ID3v2 tag contents into frames
+func id3Frames(data []byte, version byte) []id3Frame {
+	frames := []id3Frame{}
+	// padding of zeros may follow the frames
+	for len(data) >= 10 && data[0] != 0 {
+		size := int(binary.BigEndian.Uint32(data[4:8]))
+		if version == 4 {
+			size = syncsafe(data[4:8])
+		}
+		if size > len(data)-10 {
+			break
+		}
+		frames = append(frames, id3Frame{id: string(data[:4]), data: data[10 : 10+size]})
+		data = data[10+size:]
+	}
+	return frames
+}
+
+// decodes text frame, first byte of which is the encoding
+func decodeID3Text(frame []byte) string {
+	if len(frame) == 0 {
+		return ""
+	}
+	encoding, text := frame[0], frame[1:]
+	var s string
+	switch encoding {
+	case 0:
+		// latin-1 bytes are the first 256 code points
+		runes := make([]rune, len(text))
+		for i, b := range text {
+			runes[i] = rune(b)
+		}
+		s = string(runes)
+	case 1, 2:
+		bigEndian := encoding == 2
+		if len(text) >= 2 {
+			switch {
+			case text[0] == 0xfe && text[1] == 0xff:
+				bigEndian, text = true, text[2:]
+			case text[0] == 0xff && text[1] == 0xfe:
+				bigEndian, text = false, text[2:]
+			}
+		}
+		units := make([]uint16, len(text)/2)
+		for i := range units {
+			if bigEndian {
+				units[i] = binary.BigEndian.Uint16(text[2*i:])
+			} else {
+				units[i] = binary.LittleEndian.Uint16(text[2*i:])
+			}
+		}
+		s = string(utf16.Decode(units))
+	default:
+		s = string(text)
+	}
+	// strings may be terminated with zero
+	s, _, _ = strings.Cut(s, "\x00")
+	return strings.TrimSpace(s)
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+var codecNames = map[string]string{
+	"mp3":  "MP3",
+	"flac": "FLAC",
+	"ogg":  "Ogg Vorbis",
+}
+
+// technical details of the track: codec, sample rate, bit depth, channels and peak level
+func (t track) info() string {
+	details := []string{}
+	if codec, ok := codecNames[t.audioFormat]; ok {
+		details = append(details, codec)
+	}
+	details = append(details, fmt.Sprintf("%d Hz", t.format.SampleRate))
+	// lossy decoders report precision of their output, not of the source
+	if t.audioFormat == "flac" {
+		details = append(details, fmt.Sprintf("%d-bit", t.format.Precision*8))
+	}
+	switch t.format.NumChannels {
+	case 1:
+		details = append(details, "mono")
+	case 2:
+		details = append(details, "stereo")
+	default:
+		details = append(details, fmt.Sprintf("%d channels", t.format.NumChannels))
+	}
+	switch {
+	case t.peakScanned && t.peakDBFS <= silentPeakDBFS:
+		details = append(details, "silent")
+	case t.peakScanned:
+		details = append(details, fmt.Sprintf("peak %.1f dBFS", t.peakDBFS))
+	}
+	switch {
+	case rawOutput:
+		details = append(details, "not resampled (debug)")
+	case t.format.SampleRate != basicSampleRate:
+		details = append(details, fmt.Sprintf("resampled to %d Hz", basicSampleRate))
+	}
+	return strings.Join(details, ", ")
+}
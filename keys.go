@@ -0,0 +1,92 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// key and the action it triggers, shown in help
+type keyBinding struct {
+	keys   string
+	action string
+}
+
+var keyBindings = []keyBinding{
+	{"k, arrow up", "up"},
+	{"j, arrow down", "down"},
+	{"f", "next track"},
+	{"F", "previous track"},
+	{")", "next chapter"},
+	{"(", "previous chapter"},
+	{"[", "volume down"},
+	{"]", "volume up"},
+	{"=", "reset volume to 100%"},
+	{"L", "toggle loudness (bass boost)"},
+	{"p", "pause/unpause"},
+	{"c", "clear track queue"},
+	{"C", "remove played tracks from queue"},
+	{"r", "restart current track"},
+	{"R", "restart queue"},
+	{"~", "reverse queue"},
+	{"#", "sort upcoming tracks by track number"},
+	{"0-9", "seek to 0%-90% of the track, or type count of the next command"},
+	{"l", "set loop start, loop end, clear loop"},
+	{"s", "start current file from this position in future plays"},
+	{"S", "clear start position of current file"},
+	{"A", "toggle auto advance to the next track"},
+	{"x", "toggle consume, remove tracks from queue once played"},
+	{"X", "mark or unmark file under cursor as skipped, it is never played automatically"},
+	{"t", "play current track one more time"},
+	{"T", "clear repeats of current track"},
+	{"<Space>", "add track to queue"},
+	{"+", "add another instance of the track to queue"},
+	{"n", "play track next"},
+	{"N", "queue file under cursor and count files after it, count is typed before N"},
+	{"o", "play just finished track once more"},
+	{"d", "remove track from queue"},
+	{"a", "add directory to queue recursively, or replace queue with it if folderEnqueue is \"replace\""},
+	{"ctrl+a", "replace queue with directory, or add it if folderEnqueue is \"replace\""},
+	{"P", "load playlist of the directory"},
+	{"{", "decrease gain of queued track"},
+	{"}", "increase gain of queued track"},
+	{"<Enter>", "enter directory"},
+	{"b", "album view of the directory: (<Enter>) expands album, (<Space>) queues album or track"},
+	{"-", "directory up"},
+	{"I", "add files and directories by path, several paths are separated by spaces"},
+	{"J", "go to directory by typed path"},
+	{"g", "go to playing track"},
+	{"G", "toggle following playing track"},
+	{"m", "toggle minimal view"},
+	{"w", "show queue next to the browser"},
+	{"<Tab>", "move focus between browser and queue: (d) removes, (K) and (J) move, (<Enter>) plays track"},
+	{"v", "toggle spectrum visualizer"},
+	{"i", "show codec, sample rate, bit depth and channels of the current track"},
+	{"^", "scan peak level of the current track and show it in the info line"},
+	{"K", "toggle bar with common keys"},
+	{"Q", "toggle number of queued tracks in the header"},
+	{">", "show more entries"},
+	{"<", "show less entries"},
+	{":", "command mode: add PATH, goto N, volume N, save FILE, export FILE, keys TEXT"},
+	{".", "toggle hidden files"},
+	{"ctrl+r", "reload directory"},
+	{"ctrl+d", "debug: play tracks without resampling to check if it causes a wrong pitch or speed"},
+	{"y", "copy current track path to clipboard"},
+	{"D", "move file to trash, press twice to confirm"},
+	{"e", "rename file under cursor"},
+	{"/", "search keys by action"},
+	{"?", "toggle help"},
+	{"q, ctrl+c", "quit"},
+}
+
+// renders key bindings whose action contains the filter or keys match it
+func helpView(filter string) string {
+	filter = strings.ToLower(filter)
+	s := "controls:\n\n"
+	for _, binding := range keyBindings {
+		if filter != "" && !strings.Contains(strings.ToLower(binding.action), filter) && strings.ToLower(binding.keys) != filter {
+			continue
+		}
+		s += fmt.Sprintf("(%s) %s\n", binding.keys, binding.action)
+	}
+	return s
+}
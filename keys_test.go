@@ -0,0 +1,99 @@
+package main
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestHelpFilterIgnoresCase(t *testing.T) {
+	tests := []struct {
+		filter string
+		want   []string
+	}{
+		{"N", []string{"(n) play track next", "(N) queue file under cursor"}},
+		{"n", []string{"(n) play track next", "(N) queue file under cursor"}},
+		{"<SPACE>", []string{"(<Space>) add track to queue"}},
+		{"QUIT", []string{"(q, ctrl+c) quit"}},
+	}
+	for _, test := range tests {
+		help := helpView(test.filter)
+		for _, want := range test.want {
+			if !strings.Contains(help, want) {
+				t.Errorf("help filtered by %q misses %q:\n%s", test.filter, want, help)
+			}
+		}
+	}
+}
+
+// keys handled by the key switch of appState.Update
+func updateKeys(t *testing.T) []string {
+	t.Helper()
+	f, err := parser.ParseFile(token.NewFileSet(), "main.go", nil, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keys := []string{}
+	for _, decl := range f.Decls {
+		fn, ok := decl.(*ast.FuncDecl)
+		if !ok || fn.Name.Name != "Update" || fn.Recv == nil {
+			continue
+		}
+		ast.Inspect(fn.Body, func(n ast.Node) bool {
+			sw, ok := n.(*ast.SwitchStmt)
+			if !ok {
+				return true
+			}
+			// switch msg.String() of the key message
+			call, ok := sw.Tag.(*ast.CallExpr)
+			if !ok {
+				return true
+			}
+			if sel, ok := call.Fun.(*ast.SelectorExpr); !ok || sel.Sel.Name != "String" {
+				return true
+			}
+			for _, stmt := range sw.Body.List {
+				for _, expr := range stmt.(*ast.CaseClause).List {
+					if lit, ok := expr.(*ast.BasicLit); ok && lit.Kind == token.STRING {
+						key, _ := strconv.Unquote(lit.Value)
+						keys = append(keys, key)
+					}
+				}
+			}
+			return true
+		})
+	}
+	if len(keys) == 0 {
+		t.Fatal("key switch of Update is not found")
+	}
+	return keys
+}
+
+func TestEveryKeyHasHelp(t *testing.T) {
+	// names used in help for keys that msg.String() spells differently
+	names := map[string][]string{
+		"arrow up":   {"up"},
+		"arrow down": {"down"},
+		"<Space>":    {" "},
+		"<Enter>":    {"enter"},
+		"<Tab>":      {"tab"},
+		"0-9":        {"0", "1", "2", "3", "4", "5", "6", "7", "8", "9"},
+	}
+	documented := map[string]bool{}
+	for _, binding := range keyBindings {
+		for _, key := range strings.Split(binding.keys, ", ") {
+			documented[key] = true
+			for _, name := range names[key] {
+				documented[name] = true
+			}
+		}
+	}
+	for _, key := range updateKeys(t) {
+		if !documented[key] {
+			t.Errorf("key %q is handled but missing from keyBindings", key)
+		}
+	}
+}
@@ -1,54 +1,151 @@
 package main
 
 import (
+	"cmp"
 	"errors"
 	"flag"
 	"fmt"
+	"io"
+	"io/fs"
 	"log"
 	"math"
 	"os"
+	"os/user"
 	"path/filepath"
 	"slices"
+	"strings"
 	"time"
 
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/gopxl/beep/v2"
 	"github.com/gopxl/beep/v2/effects"
+	"github.com/gopxl/beep/v2/flac"
 	"github.com/gopxl/beep/v2/mp3"
 	"github.com/gopxl/beep/v2/speaker"
+	"github.com/gopxl/beep/v2/vorbis"
 )
 
-var supportedFormats = []string{"mp3"}
+var supportedFormats = []string{"mp3", "flac", "ogg"}
+
+// output sample rate, tracks are resampled to it
+var basicSampleRate beep.SampleRate = 44100
+
+// quality of resampling. higher values sound better but use more CPU
+var resampleQuality = 4
+
+// quit after nothing plays and no keys are pressed for this long, 0 disables it
+var idleTimeout time.Duration
+
+// how deep recursive add goes into subdirectories, 0 means no limit
+var maxDepth int
+
+// debug mode, tracks are played without resampling at any sample rate
+var rawOutput bool
+
+// change volume smoothly instead of instant jumps
+var volumeRamp bool
+
+const volumeRampDuration = 100 * time.Millisecond
+
+// silence between tracks of different albums
+var trackGap = 500 * time.Millisecond
+
+// duration of fade in when playback starts after stop, 0 disables it
+var fadeIn = 250 * time.Millisecond
+
+const (
+	defaultListSize = 16
+	minListSize     = 4
+	maxListSize     = 200
+	// range supported by beep.Resample
+	minResampleQuality = 1
+	maxResampleQuality = 64
+	minTrackGain       = -20
+	maxTrackGain       = 20
+	minSampleRate      = 8000
+	maxSampleRate      = 192000
+	minBufferMs        = 10
+	maxBufferMs        = 2000
+	minVolume          = 0
+	maxVolume          = 200
+)
 
-// sample rate for mp3
-const basicSampleRate beep.SampleRate = 44100
 const executableName = "gomusic"
-const helpString = "Usage: " + executableName + " [DIRECTORY]"
+const helpString = "Usage: " + executableName + " [DIRECTORY]..."
 
 // program pointer to send messages from other threads
 var program *tea.Program
 
 func main() {
-	speaker.Init(basicSampleRate, basicSampleRate.N(time.Second/10))
 	var directoryPath string
 	var initialVolume int
+	var minimal bool
+	var sampleRate int
+	var bufferMs int
+	var forceDelete bool
+	var logPath string
+	var printStatus bool
+	var remote string
+	var daemon bool
+	var showStats bool
+	var validatePath string
+	var repeatPath string
+	var autoplay bool
+	var pauseOnUnplug bool
+	var eventsPath string
 	curDir, err := os.Getwd()
 	if err != nil {
 		log.Fatal(err)
 	}
 	directoryPath = curDir
 	flag.IntVar(&initialVolume, "volume", 100, "set initial volume in percents(default 100)")
+	flag.BoolVar(&volumeRamp, "volume-ramp", false, "change volume smoothly instead of instant jumps")
+	flag.DurationVar(&trackGap, "gap", trackGap, "silence between tracks of different albums, tracks of one album are played gapless")
+	flag.DurationVar(&prebufferDuration, "prebuffer", 0, "decode tracks ahead of playback by the duration, for example 5s. helps with stutter on slow disks and network mounts")
+	flag.DurationVar(&fadeIn, "fade-in", fadeIn, "fade in duration when playback starts after stop, 0 disables it")
+	flag.BoolVar(&minimal, "minimal", false, "show only the status line without the file browser")
+	flag.IntVar(&sampleRate, "sample-rate", int(basicSampleRate), "output sample rate in Hz")
+	flag.BoolVar(&trimSilence, "trim-silence", false, "skip silence at the start and the end of tracks. tracks are scanned on load")
+	flag.Float64Var(&silenceThreshold, "silence-threshold", silenceThreshold, "amplitude (0..1) below which sound is treated as silence")
+	flag.BoolVar(&showStats, "stats", false, "print listening statistics of the session on quit")
+	flag.BoolVar(&printStatus, "status", false, "print status of the running instance as JSON and exit")
+	flag.StringVar(&remote, "remote", "", "send the command to the running instance, print its answer and exit")
+	flag.BoolVar(&daemon, "daemon", false, "play tracks of the directories in the background without the TUI")
+	flag.StringVar(&logPath, "log", "", "write debug log to the file")
+	flag.StringVar(&eventsPath, "events", "", "write player events to the file as JSON lines")
+	flag.BoolVar(&pauseOnUnplug, "pause-on-unplug", false, "pause when headphones are unplugged or default output changes (PulseAudio and PipeWire)")
+	flag.DurationVar(&idleTimeout, "idle-timeout", 0, "quit after nothing plays and no keys are pressed for the duration, for example 10m")
+	flag.IntVar(&maxDepth, "max-depth", 0, "add tracks from subdirectories at most N levels deep when adding recursively, 0 means no limit")
+	flag.BoolVar(&autoplay, "autoplay", false, "queue tracks of the starting directory and play them")
+	flag.StringVar(&repeatPath, "repeat", "", "play the track on repeat in minimal view")
+	flag.StringVar(&validatePath, "validate", "", "check that every track of the M3U playlist can be played and exit")
+	flag.BoolVar(&forceDelete, "force-delete", false, "delete files permanently when trash is not available")
+	flag.IntVar(&resampleQuality, "resample-quality", resampleQuality, fmt.Sprintf("resampling quality from %d to %d. higher values use more CPU", minResampleQuality, maxResampleQuality))
+	flag.IntVar(&bufferMs, "buffer-ms", 100, "speaker buffer size in milliseconds. bigger buffer fixes stutter but increases latency")
 	flag.Usage = func() {
 		fmt.Fprintln(os.Stderr, helpString)
 		fmt.Fprintln(os.Stderr, "Flags:")
 		flag.PrintDefaults()
 	}
 	flag.Parse()
+	// terminal belongs to the TUI, so log only goes to the file if requested.
+	// set up before anything is loaded, errors that end startup go to stderr
+	if logPath != "" {
+		logFile, err := tea.LogToFile(logPath, executableName)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		defer logFile.Close()
+	} else {
+		log.SetOutput(io.Discard)
+	}
 	args := flag.Args()
 	if len(args) != 0 {
-		directoryPath, err = filepath.Abs(args[0])
+		directoryPath, err = absPath(args[0])
 		if err != nil {
-			log.Fatal(err)
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
 		}
 	}
 
@@ -56,66 +153,389 @@ func main() {
 		fmt.Println(helpString)
 		os.Exit(0)
 	}
-	program = tea.NewProgram(appState{
+	if printStatus {
+		remote = "status"
+	}
+	if remote != "" {
+		answer, err := remoteCommand(remote)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		fmt.Println(answer)
+		os.Exit(0)
+	}
+	if sampleRate < minSampleRate || sampleRate > maxSampleRate {
+		fmt.Fprintf(os.Stderr, "sample rate must be between %d and %d\n", minSampleRate, maxSampleRate)
+		os.Exit(2)
+	}
+	if bufferMs < minBufferMs || bufferMs > maxBufferMs {
+		fmt.Fprintf(os.Stderr, "buffer size must be between %d and %d ms\n", minBufferMs, maxBufferMs)
+		os.Exit(2)
+	}
+	if resampleQuality < minResampleQuality || resampleQuality > maxResampleQuality {
+		fmt.Fprintf(os.Stderr, "resample quality must be between %d and %d\n", minResampleQuality, maxResampleQuality)
+		os.Exit(2)
+	}
+	if silenceThreshold < 0 || silenceThreshold >= 1 {
+		fmt.Fprintln(os.Stderr, "silence threshold must be between 0 and 1")
+		os.Exit(2)
+	}
+	if maxDepth < 0 {
+		fmt.Fprintln(os.Stderr, "max depth can't be negative")
+		os.Exit(2)
+	}
+	if fadeIn < 0 || trackGap < 0 || prebufferDuration < 0 || idleTimeout < 0 {
+		fmt.Fprintln(os.Stderr, "durations can't be negative")
+		os.Exit(2)
+	}
+	if daemon && !isDaemon() {
+		pid, err := startDaemon()
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		fmt.Printf("playing in the background, pid %d. stop it with --remote quit\n", pid)
+		os.Exit(0)
+	}
+	// daemon has no UI, so it only makes sense if it plays something
+	autoplay = autoplay || daemon
+	basicSampleRate = beep.SampleRate(sampleRate)
+	if eventsPath != "" {
+		eventsFile, err := os.OpenFile(eventsPath, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		defer eventsFile.Close()
+		events = newEventLog(eventsFile, realClock{})
+	}
+	if validatePath != "" {
+		// report is the output, debug messages would clutter it
+		log.SetOutput(io.Discard)
+		valid, err := validatePlaylist(os.Stdout, validatePath)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		if !valid {
+			os.Exit(1)
+		}
+		os.Exit(0)
+	}
+	bufferSize := basicSampleRate.N(time.Duration(bufferMs) * time.Millisecond)
+	if err := speaker.Init(basicSampleRate, bufferSize); err != nil {
+		fmt.Fprintf(os.Stderr, "no audio output device available: %v\n", err)
+		os.Exit(1)
+	}
+	cfg, err := loadConfig()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	// config is validated on load, so the theme exists
+	t, _ := buildTheme(cfg.Theme, cfg.Colors)
+	t.apply()
+	startOffsets, err = loadStartOffsets()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	skipMarks, err = loadSkipMarks()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	// library directory is used when started without arguments
+	if len(args) == 0 {
+		defaultDir := os.Getenv("GOMUSIC_DIR")
+		if defaultDir == "" {
+			defaultDir = cfg.MusicDir
+		}
+		if defaultDir != "" {
+			directoryPath, err = absPath(defaultDir)
+			if err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				os.Exit(1)
+			}
+		}
+	}
+	queue := newTrackQueue().withVolume(initialVolume)
+	queue.autoAdvance = cfg.AutoAdvance
+	queue.maxQueue = cfg.MaxQueue
+	queue.OnTrackComplete = func(t track) {
+		log.Printf("finished %s", t.path)
+		events.trackEvent(eventTrackEnded, t.path)
+	}
+	if autoplay {
+		if _, err := queue.addDir(directoryPath, false); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+	}
+	// browsing starts in the first directory, tracks from the rest are queued
+	for i := 1; i < len(args); i++ {
+		extraDir, err := absPath(args[i])
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		if _, err := queue.addDir(extraDir, false); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+	}
+	if repeatPath != "" {
+		trackPath, err := absPath(repeatPath)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		t, err := loadTrack(trackPath)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		queue.addTrack(t)
+		queue.repeatOne = true
+		minimal = true
+	}
+	if queue.len() != 0 {
+		queue.play()
+	}
+	if daemon && queue.len() == 0 {
+		fmt.Fprintln(os.Stderr, "nothing to play")
+		os.Exit(1)
+	}
+	state, err := appState{
 		cursor:      0,
 		currentDir:  directoryPath,
 		choices:     []string{},
-		tracksQueue: *newTrackQueue().withVolume(initialVolume),
-	}.updateChoices())
-	if _, err := program.Run(); err != nil {
-		fmt.Printf("%v", err)
+		tracksQueue: *queue,
+		minimal:     minimal,
+		forceDelete: forceDelete,
+		listSize:    defaultListSize,
+		config:      cfg,
+	}.updateChoices()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
 		os.Exit(1)
 	}
+	state = state.offerDirPlaylist()
+	state.watcher = newWatcher(state.tracksQueue.clock)
+	defer state.watcher.Close()
+	state.watcher.watch(state.currentDir)
+	options := []tea.ProgramOption{
+		// signals are handled by handleSignals, so resources get released
+		tea.WithoutSignalHandler(),
+		tea.WithMouseCellMotion(),
+		tea.WithFilter(func(m tea.Model, msg tea.Msg) tea.Msg {
+			lastState, _ = m.(appState)
+			return msg
+		}),
+	}
+	if daemon {
+		// the model still runs, so remote clients and track changes work as usual
+		options = append(options, tea.WithInput(nil), tea.WithoutRenderer())
+	}
+	program = tea.NewProgram(state, options...)
+	state.watcher.start(program.Send)
+	if listener := startRemote(); listener != nil {
+		defer listener.Close()
+	}
+	if pauseOnUnplug {
+		if cmd := watchOutputDevice(); cmd != nil {
+			defer cmd.Process.Kill()
+		}
+	}
+	stopSignals := handleSignals()
+	defer stopSignals()
+	model, err := runProgram()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	state, _ = model.(appState)
+	if showStats {
+		fmt.Println(state.tracksQueue.stats)
+	}
+}
+
+// last state seen by the program, resources are released from it on panic
+var lastState appState
+
+// panic of the program or of its commands when bubbletea caught it
+var errProgramPanicked = errors.New("program stopped after a panic")
+
+// runs the program. bubbletea catches panics in the program and in its commands
+// and restores the terminal, but the model is lost, so resources are released
+// from the last seen state
+func runProgram() (tea.Model, error) {
+	model, err := program.Run()
+	if model != nil && !errors.Is(err, tea.ErrProgramKilled) {
+		return model, err
+	}
+	lastState.releaseResources()
+	if err == nil {
+		err = errProgramPanicked
+	}
+	return model, err
+}
+
+// makes path absolute, expanding leading ~ or ~user to the home directory
+func absPath(path string) (string, error) {
+	if !strings.HasPrefix(path, "~") {
+		return filepath.Abs(path)
+	}
+	name, rest, _ := strings.Cut(path[1:], string(filepath.Separator))
+	var home string
+	if name == "" {
+		userHome, err := os.UserHomeDir()
+		if err != nil {
+			return "", err
+		}
+		home = userHome
+	} else {
+		u, err := user.Lookup(name)
+		if err != nil {
+			// not a user, just a name starting with ~
+			return filepath.Abs(path)
+		}
+		home = u.HomeDir
+	}
+	return filepath.Join(home, rest), nil
 }
 
 // Music track
 type track struct {
 	// path to track
 	path string
+	// decoded file and its format, the audio file of a cue sheet differs from path
+	audioPath   string
+	audioFormat string
 	// stream struct
 	stream beep.StreamSeekCloser
-	// resampled track (to avoid bugs with playback speed)
+	// resampled track (to avoid bugs with playback speed).
+	// same as stream when sample rates match
 	resampled beep.Streamer
+	// decodes resampled ahead of playback, nil without --prebuffer
+	buffer *prebuffer
 	// track format
 	format beep.Format
 	ended  bool
+	// gain in decibels applied on top of the queue volume
+	gain float64
+	// tracks inside the file if it was loaded from a cue sheet
+	cues []cueTrack
+	// chapters of the file, read from its tags
+	chapters []chapter
+	// source of the stream supports seeking
+	canSeek bool
+	// tags of the file, empty if it has none
+	title  string
+	artist string
+	album  string
+	// number of the track in its album, 0 if unknown
+	trackNum int
+	// highest sample level in decibels relative to full scale, set by the peak scan
+	peakDBFS    float64
+	peakScanned bool
+	// first and last audible samples, trimEnd is 0 when track is not trimmed
+	trimStart int
+	trimEnd   int
+	// saved sample where playback starts and restarts, 0 if none is saved.
+	// seeking still reaches everything from trimStart
+	startOffset int
+}
+
+// builds streamer that plays track from the current position of the stream
+func (t track) playback() beep.Streamer {
+	var source beep.Streamer = t.stream
+	if t.trimEnd != 0 {
+		source = beep.Take(t.trimEnd-t.stream.Position(), t.stream)
+	}
+	// resampling is not needed when track already has output sample rate
+	if t.format.SampleRate == basicSampleRate || rawOutput {
+		return source
+	}
+	return beep.Resample(resampleQuality, t.format.SampleRate, basicSampleRate, source)
 }
 
 var (
 	errFormatUnsupported = errors.New("format unsupported")
 	errFileIsNotTrack    = errors.New("file is not a track")
+	errNothingFinished   = errors.New("no track is finished yet")
+	errEmptyTrack        = errors.New("track is empty or corrupted")
 )
 
+func decodeTrack(f io.ReadCloser, fileFormat string) (beep.StreamSeekCloser, beep.Format, error) {
+	switch fileFormat {
+	case "flac":
+		return flac.Decode(f)
+	case "ogg":
+		return vorbis.Decode(f)
+	}
+	return mp3.Decode(f)
+}
+
+// lowercase extension of the file without the dot, so .MP3 is mp3
+func fileFormat(name string) string {
+	return strings.ToLower(strings.TrimPrefix(filepath.Ext(name), "."))
+}
+
+func isSupportedFormat(name string) bool {
+	return slices.Contains(supportedFormats, fileFormat(name))
+}
+
 func loadTrack(trackPath string) (track, error) {
-	fileFormat := filepath.Ext(trackPath)
-	if fileFormat != "" {
-		fileFormat = fileFormat[1:]
+	fileFormat := fileFormat(trackPath)
+	if fileFormat == "cue" {
+		return loadCueSheet(trackPath)
 	}
-	if !slices.Contains(supportedFormats, fileFormat) {
+	if !isSupportedFormat(trackPath) {
 		return track{}, errFormatUnsupported
 	}
 	s := track{}
 	s.path = trackPath
-	f, err := os.Open(trackPath)
+	s.audioPath, s.audioFormat = trackPath, fileFormat
+	f, err := openTrackFile(trackPath)
 	if err != nil {
 		return track{}, err
 	}
-	fileStat, err := f.Stat()
+
+	streamer, format, err := decodeTrack(f, fileFormat)
 	if err != nil {
-		return track{}, err
+		// decoders don't close the file when they fail
+		f.Close()
+		return track{}, fmt.Errorf("%s: %w", filepath.Base(trackPath), err)
 	}
-	if fileStat.IsDir() {
-		return track{}, errFileIsNotTrack
+	// decoders seek only inside files that support it
+	_, s.canSeek = f.(io.Seeker)
+	// nothing to play, and every ratio of position to length would divide by zero
+	if streamer.Len() <= 0 {
+		streamer.Close()
+		return track{}, fmt.Errorf("%s: %w", filepath.Base(trackPath), errEmptyTrack)
 	}
-
-	// currently supports mp3 only
-	streamer, format, err := mp3.Decode(f)
 	s.stream = streamer
 	s.format = format
-	s.resampled = beep.Resample(4, s.format.SampleRate, basicSampleRate, s.stream)
-	if err != nil {
+	// broken tags shouldn't prevent playing the track
+	if err := s.readTags(); err != nil {
+		log.Printf("failed to read tags of %s: %v", trackPath, err)
+	}
+	if trimSilence {
+		s.trimStart, s.trimEnd, err = silenceBounds(s.stream, silenceThreshold)
+		if err != nil {
+			s.stream.Close()
+			return track{}, err
+		}
+	}
+	if err := s.applyStartOffset(); err != nil {
+		s.stream.Close()
 		return track{}, err
 	}
+	s.rebuildPlayback()
+	log.Printf("loaded track %s (%d Hz, %d samples)", trackPath, s.format.SampleRate, s.stream.Len())
+	events.trackEvent(eventTrackLoaded, trackPath)
 	return s, nil
 }
 
@@ -123,6 +543,12 @@ type tracksQueue struct {
 	queue []track
 	// stream controller. allows to pause and resume tracks
 	ctrl *beep.Ctrl
+	// bass boost between controller and volume
+	loudness *lowShelf
+	// smooths volume changes
+	ramp *gainRamp
+	// keeps recent samples for the visualizer
+	tap *sampleTap
 	// stream volume. allows to control volume
 	volume effects.Volume
 	// current track index in queue
@@ -130,16 +556,47 @@ type tracksQueue struct {
 	speakerInitialized bool
 	// change of the volume in percents, for example 100 means current volume is 200%
 	volumeChange int
+	// A-B loop of the current track
+	loop abLoop
+	// how many more times the current track is played before advancing
+	repeats int
+	// play the current track again and again
+	repeatOne bool
+	// start the next track when the current one ends
+	autoAdvance bool
+	// remove tracks from the queue when they are played to the end
+	consume bool
+	// number of tracks after which played ones are removed, 0 is unlimited
+	maxQueue int
+	// nothing is playing, next started track fades in
+	stopped bool
+	// listening statistics of the session
+	stats *sessionStats
+	// detects lost audio output
+	watchdog audioWatchdog
+	// drives ticks and debounces of the UI
+	clock clock
+	// called when a track is played to the end. it is not called when the
+	// track is skipped, restarted or removed, so integrations like scrobblers
+	// can tell finished tracks from skipped ones
+	OnTrackComplete func(track)
 }
 
 func newTrackQueue() *tracksQueue {
 	queue := tracksQueue{
-		queue: make([]track, 0),
-		ctrl:  &beep.Ctrl{},
+		queue:       make([]track, 0),
+		ctrl:        &beep.Ctrl{},
+		autoAdvance: true,
+		stopped:     true,
+		stats:       newSessionStats(),
+		clock:       realClock{},
 	}
+	queue.loudness = newLowShelf(queue.ctrl, basicSampleRate, loudnessFrequency, loudnessGain)
+	queue.ramp = newGainRamp(queue.loudness)
+	queue.tap = &sampleTap{Streamer: queue.ramp}
 	queue.volume = effects.Volume{
 		// see https://github.com/gopxl/beep/wiki/Hello,-Beep!
-		Streamer: queue.ctrl,
+		Streamer: queue.tap,
 		Base:     10,
 		Volume:   0,
 		Silent:   false,
@@ -148,7 +605,7 @@ func newTrackQueue() *tracksQueue {
 }
 
 func (q *tracksQueue) withVolume(volume int) *tracksQueue {
-	q.setVolume(volume)
+	q.setVolumePercent(volume)
 	return q
 }
 
@@ -157,12 +614,26 @@ func (s *tracksQueue) getTracks() []track {
 }
 
 func (s *tracksQueue) hasTrack(trackPath string) bool {
-	for _, track := range s.queue {
+	return s.trackIndex(trackPath) != -1
+}
+
+// returns index of the track in queue or -1 if it is not queued
+func (s *tracksQueue) trackIndex(trackPath string) int {
+	for i, track := range s.queue {
 		if track.path == trackPath {
-			return true
+			return i
 		}
 	}
-	return false
+	return -1
+}
+
+// sets gain of the track in decibels
+func (s *tracksQueue) setTrackGain(index int, db float64) {
+	if index < 0 || index >= s.len() {
+		return
+	}
+	s.queue[index].gain = max(minTrackGain, min(db, maxTrackGain))
+	s.rebuildStreamer()
 }
 
 func (s *tracksQueue) getCurrentTrack() (track, bool) {
@@ -177,35 +648,152 @@ func (s *tracksQueue) getCurrentTrackIndex() int {
 }
 
 func (s *tracksQueue) addTrack(track track) {
+	s.makeRoom()
 	s.queue = append(s.queue, track)
+	if s.len() == 1 {
+		s.stats.started(track.path)
+	}
+	s.rebuildStreamer()
+}
+
+// inserts track at the position in queue. track inserted at or before
+// the current one is treated as already played
+func (s *tracksQueue) insertTrack(index int, t track) {
+	if removed := s.makeRoom(); removed != -1 && removed < index {
+		index--
+	}
+	index = max(0, min(index, s.len()))
+	if s.len() != 0 && index <= s.currentTrack {
+		t.ended = true
+		s.currentTrack += 1
+	}
+	s.queue = slices.Insert(s.queue, index, t)
+	if s.len() == 1 {
+		s.stats.started(t.path)
+	}
 	s.rebuildStreamer()
 }
 
+// outcome of adding many tracks at once
+type addSummary struct {
+	queued int
+	// files of unsupported formats
+	skipped int
+	// supported files that failed to load
+	failed int
+}
+
+func (r addSummary) String() string {
+	summary := fmt.Sprintf("queued %d, skipped %d (unsupported)", r.queued, r.skipped)
+	if r.failed != 0 {
+		summary += fmt.Sprintf(", failed %d", r.failed)
+	}
+	return summary
+}
+
+// queues all supported tracks inside the directory.
+// subdirectories are scanned only if recursive is set
+func (s *tracksQueue) addDir(dirPath string, recursive bool) (addSummary, error) {
+	summary := addSummary{}
+	err := walkDir(dirPath, func(trackPath string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if entry.IsDir() {
+			if trackPath != dirPath && (!recursive || maxDepth > 0 && dirDepth(dirPath, trackPath) > maxDepth) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		s.addFile(trackPath, &summary)
+		return nil
+	})
+	return summary, err
+}
+
+// queues the file unless it is queued already. outcome is counted in summary
+func (s *tracksQueue) addFile(trackPath string, summary *addSummary) {
+	if s.hasTrack(trackPath) {
+		return
+	}
+	track, err := loadTrack(trackPath)
+	if errors.Is(errFormatUnsupported, err) || errors.Is(errFileIsNotTrack, err) {
+		summary.skipped++
+		return
+	}
+	if err != nil {
+		log.Printf("failed to load %s: %v", trackPath, err)
+		events.error(trackPath, err)
+		summary.failed++
+		return
+	}
+	s.addTrack(track)
+	summary.queued++
+}
+
+// number of directories between root and its subdirectory, 1 for direct children
+func dirDepth(root string, dir string) int {
+	rel, err := filepath.Rel(root, dir)
+	if err != nil || rel == "." {
+		return 0
+	}
+	return strings.Count(rel, string(filepath.Separator)) + 1
+}
+
 // rebuilds stream sequence
 func (s *tracksQueue) rebuildStreamer() {
 	streamers := make([]beep.Streamer, 0)
-	for _, track := range s.queue {
-		if !track.ended {
-			seq := beep.Seq(track.resampled, beep.Callback(func() {
-				program.Send("f")
+	var prevPath string
+	for i, track := range s.queue {
+		// without auto advance playback stops after the current track
+		if !s.autoAdvance && i != s.currentTrack {
+			continue
+		}
+		// skipped tracks are played only if user goes to them
+		if !track.ended && (i == s.currentTrack || !skipMarks[track.path]) {
+			streamer := track.resampled
+			if track.gain != 0 {
+				streamer = &effects.Volume{
+					Streamer: streamer,
+					Base:     10,
+					Volume:   track.gain / 20,
+				}
+			}
+			// tracks of one album follow each other without a gap
+			if prevPath != "" && trackGap > 0 && !sameAlbum(prevPath, track.path) {
+				streamers = append(streamers, beep.Silence(basicSampleRate.N(trackGap)))
+			}
+			prevPath = track.path
+			seq := beep.Seq(streamer, beep.Callback(func() {
+				// callback runs under speaker lock, while UI may wait for it
+				go program.Send("f")
 			}))
 			streamers = append(streamers, seq)
 		}
 	}
 	stream := beep.Seq(streamers...)
 	speaker.Lock()
+	s.updatePrebuffers()
+	if fadeIn > 0 && s.stopped && len(streamers) != 0 {
+		s.ramp.start(0, basicSampleRate.N(fadeIn))
+	}
 	s.ctrl.Streamer = stream
 	speaker.Unlock()
+	s.stopped = len(streamers) == 0
 }
 
 func (s *tracksQueue) nextTrack() {
+	s.repeats = 0
 	if s.len() != 0 {
 		s.queue[s.currentTrack].ended = true
 	}
-	if s.currentTrack+1 >= s.len() {
+	next := s.nextPlayable(s.currentTrack + 1)
+	if next == -1 {
+		s.stopped = true
 		return
 	}
-	s.currentTrack += 1
+	s.advanceTo(next)
+	log.Printf("next track %s", s.queue[s.currentTrack].path)
 	s.rebuildStreamer()
 	speaker.Clear()
 	s.play()
@@ -216,27 +804,261 @@ func (s *tracksQueue) prevTrack() {
 		return
 	}
 	s.currentTrack -= 1
+	s.repeats = 0
+	log.Printf("previous track %s", s.queue[s.currentTrack].path)
 	s.queue[s.currentTrack].ended = false
+	s.stats.started(s.queue[s.currentTrack].path)
 	s.rebuildStreamer()
 	speaker.Clear()
 	s.play()
 }
 
-func (s *tracksQueue) restartCurrentTrack() {
+// starts playing track at the index from the beginning
+func (s *tracksQueue) goToTrack(index int) {
+	if index < 0 || index >= s.len() {
+		return
+	}
+	for i := range s.queue {
+		s.queue[i].ended = i < index
+	}
+	// tracks after the index could be already played
+	for i := index; i < s.len(); i++ {
+		if s.queue[i].position() != s.queue[i].playStart() {
+			s.restartTrack(i)
+		}
+	}
+	s.currentTrack = index
+	s.repeats = 0
+	// repeats of the track count as plays too
+	s.stats.started(s.queue[index].path)
+	s.rebuildStreamer()
+	s.play()
+}
+
+// returns the last finished track. it is the current one
+// when the whole queue is played, otherwise the previous one
+func (s *tracksQueue) lastFinishedTrack() (track, bool) {
+	currentTrack, ok := s.getCurrentTrack()
+	if !ok {
+		return track{}, false
+	}
+	if currentTrack.ended {
+		return currentTrack, true
+	}
+	if s.currentTrack == 0 {
+		return track{}, false
+	}
+	return s.queue[s.currentTrack-1], true
+}
+
+// queues fresh copy of the last finished track right after the current one
+func (s *tracksQueue) requeueFinished() (track, error) {
+	finished, ok := s.lastFinishedTrack()
+	if !ok {
+		return track{}, errNothingFinished
+	}
+	t, err := loadTrack(finished.path)
+	if err != nil {
+		return track{}, err
+	}
+	t.gain = finished.gain
+	queuePlayed := s.queue[s.currentTrack].ended
+	s.insertTrack(s.currentTrack+1, t)
+	if queuePlayed {
+		// whole queue was played, so the new track becomes the current one
+		s.goToTrack(s.currentTrack + 1)
+	} else {
+		s.play()
+	}
+	return t, nil
+}
+
+func (s *tracksQueue) setAutoAdvance(autoAdvance bool) {
+	s.autoAdvance = autoAdvance
+	s.rebuildStreamer()
+}
+
+// handles the natural end of the current track
+func (s *tracksQueue) trackEnded() {
+	if s.OnTrackComplete != nil && s.len() != 0 {
+		s.OnTrackComplete(s.queue[s.currentTrack])
+	}
+	if s.repeatOne && s.len() != 0 {
+		s.goToTrack(s.currentTrack)
+		return
+	}
+	if s.repeats > 0 {
+		repeats := s.repeats - 1
+		s.goToTrack(s.currentTrack)
+		s.repeats = repeats
+		return
+	}
+	if s.len() == 0 {
+		return
+	}
+	s.queue[s.currentTrack].ended = true
+	if s.consume {
+		s.consumeTrack()
+		return
+	}
+	next := s.nextPlayable(s.currentTrack + 1)
+	if !s.autoAdvance || next == -1 {
+		s.stopped = true
+		return
+	}
+	// next track is already playing from the stream sequence,
+	// rebuilding it would break gapless playback
+	s.advanceTo(next)
+	log.Printf("next track %s", s.queue[s.currentTrack].path)
+}
+
+// removes just finished track from the queue. the track that follows it
+// takes its index, so it becomes current without rebuilding the stream
+func (s *tracksQueue) consumeTrack() {
+	finished := s.currentTrack
+	s.queue[finished].close()
+	s.queue = slices.Delete(s.queue, finished, finished+1)
+	next := s.nextPlayable(finished)
+	if next == -1 {
+		s.currentTrack = max(s.len()-1, 0)
+		s.stopped = true
+		return
+	}
+	s.advanceTo(next)
+	if !s.autoAdvance {
+		// playback stops like in trackEnded, but the finished track is gone,
+		// so the next one becomes current and waits paused until resume
+		s.pause()
+		s.rebuildStreamer()
+		return
+	}
+	log.Printf("next track %s", s.queue[s.currentTrack].path)
+}
+
+// returns track that plays after the current one ends, following the same rules as trackEnded
+func (s *tracksQueue) peekNext() (track, bool) {
+	next := s.nextIndex()
+	if next == -1 {
+		return track{}, false
+	}
+	return s.queue[next], true
+}
+
+// index of the track that plays after the current one ends, -1 if none does
+func (s *tracksQueue) nextIndex() int {
 	if s.len() == 0 {
+		return -1
+	}
+	if s.repeatOne || s.repeats > 0 {
+		return s.currentTrack
+	}
+	if !s.autoAdvance {
+		return -1
+	}
+	return s.nextPlayable(s.currentTrack + 1)
+}
+
+// tracks from one directory are treated as one album
+func sameAlbum(path1 string, path2 string) bool {
+	return filepath.Dir(path1) == filepath.Dir(path2)
+}
+
+// moves track to its start without rebuilding the stream sequence
+func (s *tracksQueue) rewindTrack(index int) {
+	t := &s.queue[index]
+	if !t.seekable() {
 		return
 	}
+	speaker.Lock()
+	t.seekStream(t.playStart())
+	t.rebuildPlayback()
+	speaker.Unlock()
+}
+
+// switches resampling of all tracks off or on. tracks continue from their positions
+func (s *tracksQueue) setRawOutput(raw bool) {
+	speaker.Lock()
+	rawOutput = raw
+	for i := range s.queue {
+		s.queue[i].rebuildPlayback()
+	}
+	speaker.Unlock()
+	s.rebuildStreamer()
+}
+
+// reverses order of the queue. current track keeps playing
+func (s *tracksQueue) reverse() {
+	if s.len() == 0 {
+		return
+	}
+	slices.Reverse(s.queue)
+	s.currentTrack = s.len() - 1 - s.currentTrack
+	for i := range s.queue {
+		if i < s.currentTrack {
+			s.queue[i].ended = true
+		}
+		// tracks that were played before now come next
+		if i > s.currentTrack {
+			s.queue[i].ended = false
+			s.rewindTrack(i)
+		}
+	}
+	s.rebuildStreamer()
+}
+
+// orders tracks after the current one by their track numbers. tracks of different
+// directories are kept apart, tracks without a number go after the numbered ones
+// in file name order
+func (s *tracksQueue) sortByTrackNumber() {
+	if s.currentTrack+1 >= s.len() {
+		return
+	}
+	slices.SortStableFunc(s.queue[s.currentTrack+1:], func(x, y track) int {
+		if c := strings.Compare(filepath.Dir(x.path), filepath.Dir(y.path)); c != 0 {
+			return c
+		}
+		return cmp.Or(compareTrackNumbers(x.trackNum, y.trackNum), strings.Compare(filepath.Base(x.path), filepath.Base(y.path)))
+	})
+	s.rebuildStreamer()
+}
+
+// orders by track number, tracks without a number go after numbered ones
+func compareTrackNumbers(x int, y int) int {
+	if (x == 0) != (y == 0) {
+		if x == 0 {
+			return 1
+		}
+		return -1
+	}
+	return cmp.Compare(x, y)
+}
+
+func (s *tracksQueue) restartCurrentTrack() error {
+	if s.len() == 0 {
+		return nil
+	}
+	if !s.queue[s.currentTrack].seekable() {
+		return errNotSeekable
+	}
 	s.restartTrack(s.currentTrack)
+	return nil
 }
 
+// moves track to its start. tracks that are not seekable stay as they are
 func (s *tracksQueue) restartTrack(index int) {
 	currentSong := &s.queue[index]
+	if !currentSong.seekable() {
+		return
+	}
 	currentSong.ended = false
-	ended := currentSong.stream.Position() == currentSong.stream.Len()
-	currentSong.stream.Seek(0)
-	if ended {
+	speaker.Lock()
+	ended := currentSong.position() == currentSong.stream.Len()
+	currentSong.seekStream(currentSong.playStart())
+	speaker.Unlock()
+	// trimmed track has to be rebuilt because it counts played samples
+	if ended || currentSong.trimEnd != 0 {
 		speaker.Lock()
-		currentSong.resampled = beep.Resample(4, basicSampleRate, currentSong.format.SampleRate, currentSong.stream)
+		currentSong.rebuildPlayback()
 		speaker.Unlock()
 		s.rebuildStreamer()
 		s.play()
@@ -258,28 +1080,41 @@ func (s *tracksQueue) play() {
 	}
 }
 
-func (s *tracksQueue) setVolume(percents int) {
-	percents = percents - 100
-	if percents < -100 {
-		percents = -100
-	}
-	s.volumeChange = percents
-	if s.volumeChange == -100 {
-		s.volume.Silent = true
-	} else {
-		s.volume.Silent = false
+// sets volume to the exact value in percents, clamped to the supported range
+func (s *tracksQueue) setVolumePercent(percents int) {
+	percents = min(max(percents, minVolume), maxVolume)
+	speaker.Lock()
+	oldVolume, wasSilent := s.volume.Volume, s.volume.Silent
+	s.volumeChange = percents - 100
+	s.volume.Silent = percents == 0
+	// 100% is gain 1, Base of the volume is 10
+	s.volume.Volume = math.Log10(float64(percents)) - 2
+	if volumeRamp && !wasSilent && !s.volume.Silent {
+		// new volume applies instantly, so ramp starts from the old level
+		s.ramp.start(math.Pow(s.volume.Base, oldVolume-s.volume.Volume), basicSampleRate.N(volumeRampDuration))
 	}
-	s.volume.Volume = math.Log10(100+float64(s.volumeChange)) - 2
+	speaker.Unlock()
 	speaker.Clear()
 	speaker.Play(&s.volume)
+	events.volumeChanged(s.getVolumePercents())
 }
 
 func (s *tracksQueue) changeVolume(percents int) {
-	s.setVolume(s.volumeChange + percents + 100)
+	s.setVolumePercent(s.volumeChange + percents + 100)
 }
 
+// volume is set only in whole percents, so it is reported as set
+// instead of being recomputed from the gain, which drifts after rounding
 func (s tracksQueue) getVolumePercents() int {
-	return int(math.Round(100 * math.Pow(s.volume.Base, s.volume.Volume)))
+	return 100 + s.volumeChange
+}
+
+func (s *tracksQueue) toggleLoudness() {
+	s.setLoudness(!s.loudness.Enabled)
+}
+
+func (s *tracksQueue) loud() bool {
+	return s.loudness.Enabled
 }
 
 func (s *tracksQueue) unpause() {
@@ -298,59 +1133,271 @@ func (s *tracksQueue) pause() {
 	speaker.Unlock()
 }
 
+// pauses or resumes playback. depends only on the queue, so it works
+// wherever the browser is, even in an empty directory
+func (s *tracksQueue) togglePause() {
+	if s.len() == 0 {
+		return
+	}
+	if s.paused() {
+		s.unpause()
+	} else {
+		s.pause()
+	}
+}
+
 func (s *tracksQueue) paused() bool {
 	return s.ctrl.Paused
 }
 
-func (s *tracksQueue) removeTrack(trackName string) {
-	trackIndex := -1
-	prev := 0
-	for i, track := range s.queue {
-		if filepath.Base(track.path) == trackName {
-			trackIndex = i
-			break
+// removes track of the file from the queue. removing the playing track
+// moves playback to the next one or stops it when no tracks are left
+func (s *tracksQueue) removeTrack(trackPath string) {
+	s.removeTrackAt(s.trackIndex(trackPath))
+}
+
+// removes the oldest played track if queue is full. queue grows over the limit
+// when nothing is played yet. returns index of the removed track or -1
+func (s *tracksQueue) makeRoom() int {
+	if s.maxQueue <= 0 || s.len() < s.maxQueue {
+		return -1
+	}
+	for i := range s.currentTrack {
+		if !s.queue[i].ended {
+			continue
 		}
-		prev = i
+		s.queue[i].close()
+		s.queue = slices.Delete(s.queue, i, i+1)
+		s.currentTrack--
+		return i
 	}
-	if trackIndex == -1 {
-		return
+	return -1
+}
+
+// removes played tracks before the current one and closes their streams.
+// returns number of removed tracks
+func (s *tracksQueue) clearPlayed() int {
+	kept := make([]track, 0, s.len())
+	removed := 0
+	for i, track := range s.queue {
+		if i < s.currentTrack && track.ended {
+			track.close()
+			removed++
+			continue
+		}
+		kept = append(kept, track)
 	}
-	s.currentTrack = prev
-	s.queue = slices.Delete(s.queue, trackIndex, trackIndex+1)
+	s.currentTrack -= removed
+	s.queue = kept
+	return removed
 }
 
 // releases all resources and cleans queue
 func (s *tracksQueue) clear() {
 	for _, track := range s.queue {
-		track.stream.Close()
+		track.close()
 	}
 	speaker.Lock()
 	s.ctrl.Streamer = nil
 	speaker.Unlock()
 	s.currentTrack = 0
 	s.queue = make([]track, 0)
+	s.stopped = true
 }
 
 type appState struct {
-	cursor      int
-	currentDir  string
-	choices     []string
+	cursor     int
+	currentDir string
+	choices    []string
+	// choices that are directories
+	dirs        map[string]bool
 	tracksQueue tracksQueue
 	showHelp    bool
+	// render only the status line
+	minimal bool
+	// show dot-prefixed files and directories
+	showHidden bool
+	// short message about the result of the last action
+	status string
+	// file waiting for delete confirmation
+	pendingDelete string
+	// allow permanent removal when trash is not available
+	forceDelete bool
+	// terminal width, 0 until it is known
+	width int
+	// number of ticks since start, drives animations
+	frame int
+	// number of visible entries of the browser
+	listSize int
+	// settings saved between runs
+	config config
+	// browser follows the playing track
+	followPlaying bool
+	// track changes requested by rapid key presses, applied at once
+	pendingSkip int
+	skipID      int
+	// text typed at the bottom line and what it is for
+	inputMode inputMode
+	input     string
+	// file that is being renamed
+	renaming string
+	// text searched in the help
+	keysFilter string
+	// show spectrum of the playing sound
+	visualizer bool
+	// show technical details of the current track
+	showInfo bool
+	// playlist of the current directory that is loaded on confirmation
+	pendingPlaylist string
+	// refreshes the browser when files of the current directory change
+	watcher *dirWatcher
+	// tracks are shown grouped by albums instead of directories
+	albumView   bool
+	albumsRoot  string
+	albums      []album
+	albumCursor int
+	// queue is shown next to the browser
+	showQueue bool
+	// keys act on the queue instead of the browser
+	queueFocus  bool
+	queueCursor int
+	// last time something played or user pressed a key, for the idle timeout
+	lastActivity time.Time
+	// digits typed before a command, 0 if none are typed
+	count   int
+	countID int
 }
 
 func (a appState) Init() tea.Cmd {
-	// Just return `nil`, which means "no I/O right now, please."
-	return nil
+	return tea.Batch(tea.SetWindowTitle(a.windowTitle()), a.tick())
+}
+
+// interval between redraws of animated parts of the UI
+const tickInterval = 200 * time.Millisecond
+
+type tickMsg time.Time
+
+func (a appState) tick() tea.Cmd {
+	return clockTick(a.tracksQueue.clock, tickInterval, func(t time.Time) tea.Msg {
+		return tickMsg(t)
+	})
+}
+
+// terminal title showing the current track
+func (a appState) windowTitle() string {
+	currentTrack, ok := a.tracksQueue.getCurrentTrack()
+	if !ok {
+		return ""
+	}
+	return currentTrack.displayName() + " - " + executableName
+}
+
+// clears terminal title and exits
+func quit() tea.Cmd {
+	return tea.Sequence(tea.SetWindowTitle(""), tea.Quit)
 }
 
 func (a appState) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	prevTitle := a.windowTitle()
+	prevTrack, _ := a.tracksQueue.getCurrentTrack()
+	prevDir := a.currentDir
+	var cmds []tea.Cmd
 	switch msg := msg.(type) {
 
 	case string:
-		a.tracksQueue.nextTrack()
+		a.tracksQueue.trackEnded()
+	case tickMsg:
+		now := time.Time(msg)
+		if a.lastActivity.IsZero() || a.tracksQueue.playingPath() != "" {
+			a.lastActivity = now
+		}
+		if idleTimeout > 0 && now.Sub(a.lastActivity) >= idleTimeout {
+			log.Printf("idle for %v, quitting", idleTimeout)
+			a.releaseResources()
+			return a, quit()
+		}
+		a.frame++
+		a.tracksQueue.checkLoop()
+		a.tracksQueue.stats.observe(a.tracksQueue.playingPath() != "", time.Time(msg))
+		if a.tracksQueue.checkOutput(time.Time(msg)) {
+			a.status = "audio output resumed"
+		}
+		cmds = append(cmds, a.tick())
+	case tea.WindowSizeMsg:
+		a.width = msg.Width
+	case tea.MouseMsg:
+		a.lastActivity = a.tracksQueue.clock.Now()
+		a = a.updateMouse(msg)
+	case albumsScannedMsg:
+		if msg.root == a.albumsRoot {
+			a.albums = msg.albums
+			if msg.err != nil {
+				a.status = msg.err.Error()
+			}
+		}
+	case peakScannedMsg:
+		if msg.err != nil {
+			a.status = msg.err.Error()
+			break
+		}
+		a.tracksQueue.setPeak(msg.path, msg.peak)
+		a.status = ""
+	case remoteControlMsg:
+		a = a.applyRemote(msg.command)
+	case shutdownMsg:
+		a.releaseResources()
+		return a, quit()
+	case outputChangedMsg:
+		if a.tracksQueue.playingPath() != "" {
+			a.tracksQueue.pause()
+			a.status = "paused, audio output changed"
+		}
+	case dirChangedMsg:
+		if msg.dir == a.currentDir {
+			a = a.navigate(a.reloadChoices())
+		}
+	case statusRequest:
+		msg.reply <- a.tracksQueue.snapshot()
+	case trackChangeMsg:
+		if msg.id == a.skipID {
+			a = a.applySkip()
+		}
+	case countTimeoutMsg:
+		if msg.id == a.countID {
+			a = a.expireCount()
+		}
 	// Is it a key press?
 	case tea.KeyMsg:
+		a.lastActivity = a.tracksQueue.clock.Now()
+		a.status = ""
+		if msg.String() != "D" {
+			a.pendingDelete = ""
+		}
+		if a.count != 0 && !isCountKey(msg.String()) {
+			a = a.expireCount()
+		}
+		if a.inputMode != inputNone {
+			a = a.updateInput(msg)
+			break
+		}
+		// text pasted outside of inputs is usually a file dropped into the terminal
+		if msg.Paste {
+			a.inputMode = inputAddPaths
+			a.input = string(msg.Runes)
+			break
+		}
+		if a.queueFocus && !a.showHelp {
+			var handled bool
+			if a, handled = a.updateQueuePane(msg); handled {
+				break
+			}
+		}
+		if a.albumView && !a.showHelp {
+			var handled bool
+			if a, handled = a.updateAlbumView(msg); handled {
+				break
+			}
+		}
 
 		// Cool, what was the actual key pressed?
 		switch msg.String() {
@@ -358,119 +1405,483 @@ func (a appState) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		// These keys should exit the program.
 		case "ctrl+c", "q":
 			a.releaseResources()
-			return a, tea.Quit
+			return a, quit()
 
 		// The "up" and "k" keys move the cursor up
 		case "up", "k":
 			if a.cursor > 0 {
 				a.cursor--
+			} else if a.config.WrapCursor {
+				a.cursor = max(len(a.choices)-1, 0)
 			}
 		case "r":
-			a.tracksQueue.restartCurrentTrack()
+			if err := a.tracksQueue.restartCurrentTrack(); err != nil {
+				a.status = err.Error()
+			}
 		case "R":
 			a.tracksQueue.restartQueue()
 		case "d":
 			if len(a.choices) != 0 {
-				a.tracksQueue.removeTrack(a.choices[a.cursor])
+				a.tracksQueue.removeTrack(filepath.Join(a.currentDir, a.choices[a.cursor]))
 			}
 		// The "down" and "j" keys move the cursor down
 		case "down", "j":
 			if a.cursor < len(a.choices)-1 {
 				a.cursor++
+			} else if a.config.WrapCursor {
+				a.cursor = 0
 			}
 		case "f":
-			a.tracksQueue.nextTrack()
+			if a.pendingSkip == 0 && a.tracksQueue.seekCue(1) {
+				break
+			}
+			var cmd tea.Cmd
+			a, cmd = a.skipTracks(1)
+			cmds = append(cmds, cmd)
 		case "F":
-			a.tracksQueue.prevTrack()
+			if a.pendingSkip == 0 && a.tracksQueue.seekCue(-1) {
+				break
+			}
+			var cmd tea.Cmd
+			a, cmd = a.skipTracks(-1)
+			cmds = append(cmds, cmd)
 		case "-":
-			a = a.goUpDir().updateChoices()
+			a = a.navigate(a.goUpDir().updateChoices())
 		// The "enter" key and the spacebar (a literal space) toggle
 		// the selected state for the item that the cursor is pointing at.
-		case " ":
+		case " ", "+":
+			// "+" adds another instance of already queued track
+			duplicate := msg.String() == "+"
+			var loaded track
+			var ok bool
+			a, loaded, ok = a.loadCursorTrack(duplicate)
+			if !ok {
+				break
+			}
+			a.tracksQueue.addTrack(loaded)
+			a.tracksQueue.play()
+			if !duplicate && a.config.AdvanceCursorOnQueue && a.cursor+1 < len(a.choices) {
+				a.cursor++
+			}
+		case "n":
+			var loaded track
+			var ok bool
+			a, loaded, ok = a.loadCursorTrack(false)
+			if !ok {
+				break
+			}
+			next := a.tracksQueue.getCurrentTrackIndex() + 1
+			if a.tracksQueue.len() == 0 {
+				next = 0
+			}
+			a.tracksQueue.insertTrack(next, loaded)
+			a.tracksQueue.play()
+			a.status = "playing next: " + filepath.Base(loaded.path)
+		case "c":
+			a.tracksQueue.clear()
+		case "p":
+			a.tracksQueue.togglePause()
+		case "]":
+			a.tracksQueue.changeVolume(10)
+		case "[":
+			a.tracksQueue.changeVolume(-10)
+		case "=":
+			a.tracksQueue.setVolumePercent(100)
+			a.status = "volume reset to 100%"
+		case "?":
+			a.showHelp = !a.showHelp
+			a.keysFilter = ""
+		case "/":
+			a.showHelp = true
+			a.inputMode = inputHelpFilter
+			a.input = a.keysFilter
+		case "m":
+			a.minimal = !a.minimal
+		case "v":
+			a.visualizer = !a.visualizer
+		case "i":
+			a.showInfo = !a.showInfo
+		case "^":
+			currentTrack, ok := a.tracksQueue.getCurrentTrack()
+			if !ok {
+				break
+			}
+			a.showInfo = true
+			a.status = "scanning peak of " + filepath.Base(currentTrack.path)
+			cmds = append(cmds, scanPeakCmd(currentTrack))
+		case ".":
+			a.showHidden = !a.showHidden
+			a = a.navigate(a.updateChoices())
+		case "D":
+			if len(a.choices) == 0 {
+				break
+			}
+			fileName := a.choices[a.cursor]
+			filePath := filepath.Join(a.currentDir, fileName)
+			if a.pendingDelete != filePath {
+				a.pendingDelete = filePath
+				a.status = "press D again to move " + fileName + " to trash"
+				break
+			}
+			a.pendingDelete = ""
+			if a.tracksQueue.hasTrack(filePath) {
+				a.tracksQueue.removeTrack(filePath)
+			}
+			permanent, err := deleteFile(filePath, a.forceDelete)
+			if err != nil {
+				log.Printf("failed to delete %s: %v", filePath, err)
+				a.status = err.Error()
+				break
+			}
+			a.status = "moved to trash: " + fileName
+			if permanent {
+				a.status = "deleted permanently: " + fileName
+			}
+			a = a.navigate(a.updateChoices())
+		case "a", "ctrl+a":
+			dirPath := a.currentDir
+			if len(a.choices) != 0 {
+				cursorPath := filepath.Join(a.currentDir, a.choices[a.cursor])
+				if browsable, err := isBrowsable(cursorPath); err == nil && browsable {
+					dirPath = cursorPath
+				}
+			}
+			// ctrl+a does what "a" does not
+			replace := (a.config.FolderEnqueue == folderEnqueueReplace) != (msg.String() == "ctrl+a")
+			a = a.enqueueDir(dirPath, replace)
+		case ":":
+			a.inputMode = inputCommand
+		case "J":
+			a.inputMode = inputGoToPath
+		case "I":
+			a.inputMode = inputAddPaths
+		case "N":
+			var count int
+			a, count = a.takeCount(1)
+			if len(a.choices) != 0 {
+				a = a.queueFromCursor(count)
+			}
+		case "e":
 			if len(a.choices) == 0 {
 				break
 			}
-			trackPath := filepath.Join(a.currentDir, a.choices[a.cursor])
-			if a.tracksQueue.hasTrack(trackPath) {
+			a.renaming = filepath.Join(a.currentDir, a.choices[a.cursor])
+			a.inputMode = inputRename
+			a.input = a.choices[a.cursor]
+		case "l":
+			if err := a.tracksQueue.toggleLoopPoint(); err != nil {
+				a.status = err.Error()
+			}
+		case "0", "1", "2", "3", "4", "5", "6", "7", "8", "9":
+			digit := int(msg.String()[0] - '0')
+			// like in vim, 0 starts no count
+			if digit == 0 && a.count == 0 {
+				a = a.seekDigit(0)
 				break
 			}
-			track, err := loadTrack(trackPath)
-			if errors.Is(errFormatUnsupported, err) {
+			var cmd tea.Cmd
+			a, cmd = a.typeCount(digit)
+			cmds = append(cmds, cmd)
+		case "g":
+			a = a.jumpToPlaying()
+		case "G":
+			a.followPlaying = !a.followPlaying
+			if a.followPlaying {
+				a = a.jumpToPlaying()
+				a.status = "follow playing on"
+			} else {
+				a.status = "follow playing off"
+			}
+		case "C":
+			a.status = fmt.Sprintf("removed %d played tracks", a.tracksQueue.clearPlayed())
+		case "~":
+			if a.tracksQueue.len() != 0 {
+				a.tracksQueue.reverse()
+				a.status = "queue reversed"
+			}
+		case "#":
+			if a.tracksQueue.len() != 0 {
+				a.tracksQueue.sortByTrackNumber()
+				a.status = "queue sorted by track number"
+			}
+		case "o":
+			t, err := a.tracksQueue.requeueFinished()
+			if err != nil {
+				a.status = err.Error()
 				break
 			}
-			if errors.Is(errFileIsNotTrack, err) {
+			a.status = "one more time: " + filepath.Base(t.path)
+		case "A":
+			a.config.AutoAdvance = !a.config.AutoAdvance
+			a.tracksQueue.setAutoAdvance(a.config.AutoAdvance)
+			if err := a.config.save(); err != nil {
+				a.status = err.Error()
+			} else if a.config.AutoAdvance {
+				a.status = "auto advance on"
+			} else {
+				a.status = "auto advance off"
+			}
+		case "K":
+			a.config.Keybar = !a.config.Keybar
+			if err := a.config.save(); err != nil {
+				a.status = err.Error()
+			}
+		case "Q":
+			a.config.QueueBadge = !a.config.QueueBadge
+			if err := a.config.save(); err != nil {
+				a.status = err.Error()
+			}
+		case "t":
+			if a.tracksQueue.len() != 0 {
+				a.tracksQueue.repeats++
+			}
+		case "T":
+			a.tracksQueue.repeats = 0
+			a.tracksQueue.repeatOne = false
+		case "(", ")":
+			delta := 1
+			if msg.String() == "(" {
+				delta = -1
+			}
+			if !a.tracksQueue.seekChapter(delta) {
+				a.status = "no more chapters"
+			}
+		case "w":
+			a.showQueue = !a.showQueue
+			a.queueFocus = false
+		case "tab":
+			a.showQueue = true
+			a.queueFocus = !a.queueFocus
+			a.queueCursor = max(0, min(a.queueCursor, a.tracksQueue.len()-1))
+		case "b":
+			var cmd tea.Cmd
+			a, cmd = a.openAlbumView()
+			cmds = append(cmds, cmd)
+		case "ctrl+d":
+			a.tracksQueue.setRawOutput(!rawOutput)
+			if rawOutput {
+				a.status = "debug: resampling is off, tracks play at the output sample rate"
+			} else {
+				a.status = "debug: resampling is on"
+			}
+		case "ctrl+r":
+			a = a.navigate(a.reloadChoices())
+		case "s", "S":
+			var status string
+			var err error
+			if msg.String() == "s" {
+				status, err = a.tracksQueue.setStartOffset()
+			} else {
+				status, err = a.tracksQueue.clearStartOffset()
+			}
+			if err != nil {
+				a.status = err.Error()
+			} else {
+				a.status = status
+			}
+		case "X":
+			if len(a.choices) == 0 || a.dirs[a.choices[a.cursor]] {
 				break
 			}
+			path := filepath.Join(a.currentDir, a.choices[a.cursor])
+			marked, err := toggleSkipMark(path)
 			if err != nil {
-				a.releaseResources()
-				log.Println(err)
-				return a, tea.Quit
+				a.status = err.Error()
+				break
 			}
-			a.tracksQueue.addTrack(track)
-			a.tracksQueue.play()
-			if a.cursor+1 < len(a.choices) {
-				a.cursor++
+			a.tracksQueue.rebuildStreamer()
+			if !marked {
+				a.status = "stopped skipping " + a.choices[a.cursor]
+				break
 			}
-		case "c":
-			a.tracksQueue.clear()
-		case "p":
+			a.status = "skipping " + a.choices[a.cursor]
+			if currentTrack, ok := a.tracksQueue.getCurrentTrack(); ok && currentTrack.path == path {
+				a.tracksQueue.nextTrack()
+			}
+		case "x":
+			a.tracksQueue.consume = !a.tracksQueue.consume
+			if a.tracksQueue.consume {
+				a.status = "consume on"
+			} else {
+				a.status = "consume off"
+			}
+		case "P":
+			if a.pendingPlaylist != "" {
+				a = a.loadPlaylist(a.pendingPlaylist)
+				a.pendingPlaylist = ""
+			}
+		case ">":
+			a.listSize = min(a.listSize+2, maxListSize)
+		case "<":
+			a.listSize = max(a.listSize-2, minListSize)
+		case "L":
+			a.tracksQueue.toggleLoudness()
+		case "{", "}":
 			if len(a.choices) == 0 {
 				break
 			}
-			if a.tracksQueue.paused() {
-				a.tracksQueue.unpause()
+			index := a.tracksQueue.trackIndex(filepath.Join(a.currentDir, a.choices[a.cursor]))
+			if index == -1 {
+				break
+			}
+			step := 1.0
+			if msg.String() == "{" {
+				step = -1
+			}
+			a.tracksQueue.setTrackGain(index, a.tracksQueue.getTracks()[index].gain+step)
+			a.status = fmt.Sprintf("gain: %+.0f dB", a.tracksQueue.getTracks()[index].gain)
+		case "y":
+			currentTrack, ok := a.tracksQueue.getCurrentTrack()
+			if !ok {
+				break
+			}
+			if err := copyToClipboard(currentTrack.path); err != nil {
+				a.status = err.Error()
 			} else {
-				a.tracksQueue.pause()
+				a.status = "copied: " + currentTrack.path
 			}
-		case "]":
-			a.tracksQueue.changeVolume(10)
-		case "[":
-			a.tracksQueue.changeVolume(-10)
-		case "?":
-			a.showHelp = !a.showHelp
 		case "enter":
-			a = a.goToCursorDir().updateChoices()
+			next, err := a.goToCursorDir()
+			if err == nil {
+				next, err = next.updateChoices()
+			}
+			a = a.navigate(next, err)
 		}
 
 	}
 
+	if a.currentDir != prevDir {
+		a = a.offerDirPlaylist()
+		a.watcher.watch(a.currentDir)
+	}
 	// Return the updated model to the Bubble Tea runtime for processing.
-	// Note that we're not returning a command.
-	return a, nil
+	if currentTrack, ok := a.tracksQueue.getCurrentTrack(); ok && currentTrack.path != prevTrack.path {
+		events.trackEvent(eventTrackStarted, currentTrack.path)
+		if a.followPlaying {
+			a = a.jumpToPlaying()
+		}
+	}
+	if title := a.windowTitle(); title != prevTitle {
+		cmds = append(cmds, tea.SetWindowTitle(title))
+	}
+	return a, tea.Batch(cmds...)
+}
+
+// renders status of the player shown above the progress bar
+func (a appState) headerView() string {
+	s := fmt.Sprintf("volume: %d", a.tracksQueue.getVolumePercents())
+	// queue pane already shows the queue
+	if a.config.QueueBadge && !a.showQueue && !a.minimal {
+		s = fmt.Sprintf("[queue: %d] ", a.tracksQueue.len()) + s
+	}
+	if a.tracksQueue.loud() {
+		s += ", LOUD"
+	}
+	if a.tracksQueue.repeatOne {
+		s += ", repeat: one"
+	} else if a.tracksQueue.repeats != 0 {
+		s += fmt.Sprintf(", repeat: %d", a.tracksQueue.repeats)
+	}
+	if a.tracksQueue.consume {
+		s += ", consume"
+	}
+	currentTrack, ok := a.tracksQueue.getCurrentTrack()
+	if ok {
+		s += ", playing: "
+		title := currentTrack.displayName()
+		pos, _, _ := a.tracksQueue.position()
+		if cue := currentTrack.cueAt(pos); cue != -1 {
+			title += " - " + currentTrack.cues[cue].title
+		}
+		if ch := currentTrack.chapterAt(pos); ch != -1 {
+			title += " - " + currentTrack.chapters[ch].title
+		}
+		if next, ok := a.tracksQueue.peekNext(); ok {
+			title += ", next: " + next.displayName()
+		}
+		s += marquee(title, a.width-len(s), a.frame)
+	}
+	return headerStyle.Render(s)
 }
 
 func (a appState) View() string {
 	if a.showHelp {
-		// The header
-		s := fmt.Sprint("controls:\n\n")
-		s += "(k) or (arrow up) up\n"
-		s += "(j) or (arrow down) down\n"
-		s += "(f) next track\n"
-		s += "(F) previous track\n"
-		s += "([) volume down\n"
-		s += "(]) volume up\n"
-		s += "(p) pause/unpause\n"
-		s += "(c) clear track queue\n"
-		s += "(r) restart current track\n"
-		s += "(R) restart queue\n"
-		s += "(<Space>) add track to queue\n"
-		s += "(d) remove track from queue\n"
-		s += "(<Enter>) enter directory\n"
-		s += "(-) directory up\n"
-		s += "\nPress q to quit, ? to toggle help\n"
+		s := helpView(a.helpFilter())
+		if a.inputMode == inputHelpFilter {
+			return s + "\n" + a.inputMode.prompt() + a.input + "\n"
+		}
+		return s + "\nPress q to quit, ? to toggle help, / to filter\n"
+	}
+	s := a.headerView()
+	if progress := a.progressLine(); progress != "" {
+		s += "\n" + progressStyle.Render(progress)
+	}
+	if a.visualizer && a.tracksQueue.playingPath() != "" {
+		bars := maxProgressBarWidth
+		if a.width > 0 {
+			bars = min(bars, a.width)
+		}
+		s += "\n" + a.tracksQueue.spectrumLine(bars)
+	}
+	if currentTrack, ok := a.tracksQueue.getCurrentTrack(); a.showInfo && ok {
+		s += "\n" + dimStyle.Render(currentTrack.info())
+	}
+	if a.minimal {
+		if a.status != "" {
+			s += "\n" + a.status
+		}
+		return s + "\n"
+	}
+	s += "\n" + a.status + " \n"
+
+	list := a.browserView()
+	if a.albumView {
+		list = a.albumListView()
+	}
+	if a.showQueue {
+		list = a.splitView(list)
+	}
+	s += list
+
+	// The footer
+	if a.inputMode != inputNone {
+		s += "\n" + a.inputMode.prompt() + a.input + "\n"
 		return s
 	}
-	// The header
-	s := fmt.Sprintf("volume: %d", a.tracksQueue.getVolumePercents())
-	currentTrack, ok := a.tracksQueue.getCurrentTrack()
-	if ok {
-		s = fmt.Sprintf("%s, playing: %s\n \n", s, filepath.Base(currentTrack.path))
+	if a.config.Keybar {
+		s += "\n" + keybar + "\n"
+		return s
+	}
+	s += "\nPress q to quit, ? to toggle help\n"
+
+	// Send the UI for rendering
+	return s
+}
+
+// adds tracks of the directory and its subdirectories to the queue.
+// with replace the queue is cleared first and playback starts from the top
+func (a appState) enqueueDir(dirPath string, replace bool) appState {
+	if replace {
+		a.tracksQueue.clear()
+	}
+	summary, err := a.tracksQueue.addDir(dirPath, true)
+	if err != nil {
+		log.Printf("failed to add %s: %v", dirPath, err)
+		a.status = err.Error()
 	} else {
-		s += "\n \n"
+		a.status = summary.String()
+	}
+	if summary.queued != 0 {
+		a.tracksQueue.play()
 	}
+	return a
+}
 
+// renders entries of the current directory around the cursor
+func (a appState) browserView() string {
+	s := ""
 	// Iterate over our choices
-	choicesWindowSize := 16
+	choicesWindowSize := a.listSize
 	choicesWindowStart := 0
 	choicesWindowEnd := len(a.choices)
 	if a.cursor-choicesWindowSize/2 > choicesWindowStart {
@@ -484,11 +1895,12 @@ func (a appState) View() string {
 		// Is the cursor pointing at this choice?
 		cursor := " " // no cursor
 		if a.cursor == i {
-			cursor = ">" // cursor!
+			cursor = cursorStyle.Render(">") // cursor!
 		}
 
 		// Is this choice selected?
 		checked := " " // not selected
+		gain := ""
 		for j, track := range a.tracksQueue.getTracks() {
 			if filepath.Base(track.path) != a.choices[i] {
 				continue
@@ -498,26 +1910,149 @@ func (a appState) View() string {
 			} else {
 				checked = ">"
 			}
+			if track.gain != 0 {
+				gain = fmt.Sprintf(" (%+.0f dB)", track.gain)
+			}
 			break
 		}
 
+		name := a.choices[i]
+		switch {
+		case checked == "*":
+			name = playingStyle.Render(name)
+		case checked == ">":
+			name = queuedStyle.Render(name)
+		case !a.dirs[name] && !isPlayable(name):
+			name = dimStyle.Render(name)
+		}
+		if skipMarks[filepath.Join(a.currentDir, a.choices[i])] {
+			name += dimStyle.Render(" (skip)")
+		}
+
 		// Render the row
-		s += fmt.Sprintf("%s [%s] %s\n", cursor, checked, a.choices[i])
+		s += fmt.Sprintf("%s [%s] %s%s\n", cursor, checked, name, gain)
 	}
+	return s
+}
 
-	// The footer
-	s += "\nPress q to quit, ? to toggle help\n"
+// the most used keys shown in the footer
+const keybar = "(space) add  (f) next  (p) pause  ([ ]) volume  (enter) open  (-) up  (?) help  (q) quit"
 
-	// Send the UI for rendering
-	return s
+// filter of the help, follows the input while it is typed
+func (a appState) helpFilter() string {
+	if a.inputMode == inputHelpFilter {
+		return a.input
+	}
+	return a.keysFilter
 }
 
 func (a appState) releaseResources() {
 	a.tracksQueue.clear()
 }
 
-func (a appState) exitError(err error) {
-	panic(err)
+// applies result of navigation. on error the state stays
+// unchanged and the error is shown, so user can retry or go elsewhere
+func (a appState) navigate(next appState, err error) appState {
+	if err != nil {
+		log.Printf("failed to open %s: %v", next.currentDir, err)
+		events.error(next.currentDir, err)
+		a.status = err.Error()
+		return a
+	}
+	return next
+}
+
+// delay after the last next/previous key press before the track is changed
+const trackChangeDebounce = 150 * time.Millisecond
+
+// applies pending track change if no other change was requested after it
+type trackChangeMsg struct {
+	id int
+}
+
+// requests track change. rapid requests are merged into one
+func (a appState) skipTracks(delta int) (appState, tea.Cmd) {
+	a.pendingSkip += delta
+	a.skipID++
+	id := a.skipID
+	if a.pendingSkip > 1 || a.pendingSkip < -1 {
+		a.status = fmt.Sprintf("skip %+d", a.pendingSkip)
+	}
+	return a, clockTick(a.tracksQueue.clock, trackChangeDebounce, func(time.Time) tea.Msg {
+		return trackChangeMsg{id: id}
+	})
+}
+
+func (a appState) applySkip() appState {
+	skip := a.pendingSkip
+	a.pendingSkip = 0
+	switch {
+	case skip == 1:
+		a.tracksQueue.nextTrack()
+	case skip == -1:
+		a.tracksQueue.prevTrack()
+	case skip != 0:
+		target := a.tracksQueue.getCurrentTrackIndex() + skip
+		a.tracksQueue.goToTrack(max(0, min(target, a.tracksQueue.len()-1)))
+	}
+	return a
+}
+
+// loads track under cursor. returns false if there is no supported
+// track under cursor or it is already queued and duplicates are not allowed.
+// broken file is reported in the status and skipped, the player keeps running
+func (a appState) loadCursorTrack(allowDuplicate bool) (appState, track, bool) {
+	if len(a.choices) == 0 {
+		return a, track{}, false
+	}
+	trackPath := filepath.Join(a.currentDir, a.choices[a.cursor])
+	if !allowDuplicate && a.tracksQueue.hasTrack(trackPath) {
+		return a, track{}, false
+	}
+	t, err := loadTrack(trackPath)
+	if errors.Is(errFormatUnsupported, err) || errors.Is(errFileIsNotTrack, err) {
+		return a, track{}, false
+	}
+	if err != nil {
+		log.Printf("failed to load %s: %v", trackPath, err)
+		events.error(trackPath, err)
+		a.status = err.Error()
+		return a, track{}, false
+	}
+	return a, t, true
+}
+
+// queues the file under cursor and the next files of the directory,
+// count files after the cursor one at most. directories are passed over
+func (a appState) queueFromCursor(count int) appState {
+	summary := addSummary{}
+	for i := a.cursor; i < len(a.choices) && summary.queued+summary.failed <= count; i++ {
+		if a.dirs[a.choices[i]] {
+			continue
+		}
+		a.tracksQueue.addFile(filepath.Join(a.currentDir, a.choices[i]), &summary)
+	}
+	a.status = summary.String()
+	if summary.queued != 0 {
+		a.tracksQueue.play()
+	}
+	return a
+}
+
+// opens directory of the playing track and moves cursor to it
+func (a appState) jumpToPlaying() appState {
+	currentTrack, ok := a.tracksQueue.getCurrentTrack()
+	if !ok {
+		return a
+	}
+	next := a
+	next.currentDir = filepath.Dir(currentTrack.path)
+	next, err := next.updateChoices()
+	a = a.navigate(next, err)
+	if index := slices.Index(a.choices, filepath.Base(currentTrack.path)); index != -1 {
+		a.cursor = index
+	}
+	return a
 }
 
 func (a appState) goUpDir() appState {
@@ -529,37 +2064,71 @@ func (a appState) goUpDir() appState {
 	return a
 }
 
-func (a appState) goToCursorDir() appState {
+func (a appState) goToCursorDir() (appState, error) {
 	if len(a.choices) == 0 {
-		return a
+		return a, nil
 	}
 	currentChoice := a.choices[a.cursor]
 	newDir := filepath.Join(a.currentDir, currentChoice)
-	file, err := os.Open(newDir)
-	if err != nil {
-		a.exitError(err)
-	}
-	defer file.Close()
-	info, err := file.Stat()
+	browsable, err := isBrowsable(newDir)
 	if err != nil {
-		a.exitError(err)
+		return a, err
 	}
-	if info.IsDir() {
+	if browsable {
 		a.currentDir = newDir
 		a.cursor = 0
 	}
-	return a
+	return a, nil
+}
+
+// rereads the current directory, cursor stays on the same file if it still exists
+func (a appState) reloadChoices() (appState, error) {
+	var selected string
+	if len(a.choices) != 0 {
+		selected = a.choices[a.cursor]
+	}
+	a, err := a.updateChoices()
+	if err != nil {
+		return a, err
+	}
+	if index := slices.Index(a.choices, selected); index != -1 {
+		a.cursor = index
+	}
+	return a, nil
 }
 
-func (a appState) updateChoices() appState {
-	files, err := os.ReadDir(a.currentDir)
+func (a appState) updateChoices() (appState, error) {
+	files, err := readDir(a.currentDir)
 	if err != nil {
-		a.exitError(err)
+		return a, err
 	}
-	choices := make([]string, len(files))
-	for i, file := range files {
-		choices[i] = file.Name()
+	choices := make([]string, 0, len(files))
+	dirs := make(map[string]bool)
+	for _, file := range files {
+		if !a.showHidden && strings.HasPrefix(file.Name(), ".") {
+			continue
+		}
+		choices = append(choices, file.Name())
+		if file.IsDir() {
+			dirs[file.Name()] = true
+		}
 	}
 	a.choices = choices
-	return a
+	a.dirs = dirs
+	if a.cursor >= len(a.choices) {
+		a.cursor = max(len(a.choices)-1, 0)
+	}
+	return a, nil
+}
+
+// scrolls text horizontally if it does not fit into width
+func marquee(text string, width int, frame int) string {
+	runes := []rune(text)
+	if width <= 0 || len(runes) <= width {
+		return text
+	}
+	runes = append(runes, []rune("   ")...)
+	offset := frame % len(runes)
+	scrolled := append(slices.Clone(runes[offset:]), runes[:offset]...)
+	return string(scrolled[:width])
 }
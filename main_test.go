@@ -0,0 +1,512 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"io/fs"
+	"math"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/gopxl/beep/v2"
+)
+
+// in-memory stream of silence that records whether it was closed
+type fakeStream struct {
+	pos    int
+	length int
+	closed bool
+}
+
+func (f *fakeStream) Stream(samples [][2]float64) (int, bool) {
+	n := min(len(samples), f.length-f.pos)
+	for i := range samples[:n] {
+		samples[i] = [2]float64{}
+	}
+	f.pos += n
+	return n, n > 0
+}
+
+func (f *fakeStream) Err() error    { return nil }
+func (f *fakeStream) Len() int      { return f.length }
+func (f *fakeStream) Position() int { return f.pos }
+func (f *fakeStream) Close() error {
+	f.closed = true
+	return nil
+}
+
+func (f *fakeStream) Seek(p int) error {
+	f.pos = min(max(p, 0), f.length)
+	return nil
+}
+
+// track of the given length in samples at the output sample rate
+func testTrack(path string, length int) track {
+	stream := &fakeStream{length: length}
+	t := track{
+		path:    path,
+		stream:  stream,
+		format:  beep.Format{SampleRate: basicSampleRate, NumChannels: 2, Precision: 2},
+		canSeek: true,
+	}
+	t.rebuildPlayback()
+	return t
+}
+
+// state browsing an empty directory with the tracks queued
+func testState(t *testing.T, paths ...string) appState {
+	t.Helper()
+	queue := newTrackQueue()
+	for _, path := range paths {
+		queue.addTrack(testTrack(path, int(basicSampleRate)*10))
+	}
+	return appState{
+		currentDir:  t.TempDir(),
+		choices:     []string{},
+		dirs:        map[string]bool{},
+		tracksQueue: *queue,
+		listSize:    defaultListSize,
+		config:      defaultConfig(),
+	}
+}
+
+func press(a appState, key string) appState {
+	m, _ := a.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(key)})
+	return m.(appState)
+}
+
+func TestPlaybackKeysInEmptyDirectory(t *testing.T) {
+	a := testState(t, "/music/a.mp3", "/music/b.mp3")
+
+	a = press(a, "p")
+	if !a.tracksQueue.paused() {
+		t.Error("p didn't pause")
+	}
+	a = press(a, "p")
+	if a.tracksQueue.paused() {
+		t.Error("p didn't resume")
+	}
+
+	a = press(a, "]")
+	if got := a.tracksQueue.getVolumePercents(); got != 110 {
+		t.Errorf("volume after ] = %d, want 110", got)
+	}
+	a = press(a, "[")
+	a = press(a, "[")
+	if got := a.tracksQueue.getVolumePercents(); got != 90 {
+		t.Errorf("volume after [ [ = %d, want 90", got)
+	}
+
+	a = press(a, "f")
+	m, _ := a.Update(trackChangeMsg{id: a.skipID})
+	a = m.(appState)
+	if got := a.tracksQueue.getCurrentTrackIndex(); got != 1 {
+		t.Errorf("current track after f = %d, want 1", got)
+	}
+	a = press(a, "F")
+	m, _ = a.Update(trackChangeMsg{id: a.skipID})
+	a = m.(appState)
+	if got := a.tracksQueue.getCurrentTrackIndex(); got != 0 {
+		t.Errorf("current track after F = %d, want 0", got)
+	}
+
+	current := a.tracksQueue.getTracks()[0].stream
+	current.Seek(1000)
+	a = press(a, "r")
+	if got := current.Position(); got != 0 {
+		t.Errorf("position after r = %d, want 0", got)
+	}
+}
+
+// number of open file descriptors of the process, -1 if unknown
+func openFiles(t *testing.T) int {
+	t.Helper()
+	entries, err := os.ReadDir("/proc/self/fd")
+	if err != nil {
+		return -1
+	}
+	return len(entries)
+}
+
+func TestLoadTruncatedTrack(t *testing.T) {
+	before := openFiles(t)
+	for range 20 {
+		if _, err := loadTrack(filepath.Join("testdata", "truncated.mp3")); err == nil {
+			t.Fatal("truncated track loaded without error")
+		}
+	}
+	if before == -1 {
+		t.Skip("open files can't be counted on this system")
+	}
+	if after := openFiles(t); after > before {
+		t.Errorf("%d files left open after failed loads", after-before)
+	}
+}
+
+func TestQueueCorruptFileKeepsRunning(t *testing.T) {
+	a := testState(t)
+	dir, err := filepath.Abs("testdata")
+	if err != nil {
+		t.Fatal(err)
+	}
+	a.currentDir = dir
+	a.choices = []string{"truncated.mp3"}
+	for _, key := range []string{" ", "n"} {
+		got := press(a, key)
+		if !strings.Contains(got.status, "truncated.mp3") {
+			t.Errorf("%q on corrupt file: status %q doesn't name the file", key, got.status)
+		}
+		if got.tracksQueue.len() != 0 {
+			t.Errorf("%q queued the corrupt file", key)
+		}
+	}
+}
+
+func TestVolumeSteps(t *testing.T) {
+	a := testState(t, "/music/a.mp3")
+	steps := []struct {
+		key   string
+		times int
+		want  int
+	}{
+		{"]", 3, 130},
+		{"[", 5, 80},
+		{"]", 2, 100},
+		{"[", 12, 0},
+		{"]", 25, 200},
+		{"[", 7, 130},
+	}
+	for _, step := range steps {
+		for range step.times {
+			a = press(a, step.key)
+		}
+		if got := a.tracksQueue.getVolumePercents(); got != step.want {
+			t.Errorf("after %d x %q volume = %d, want %d", step.times, step.key, got, step.want)
+		}
+		if header := fmt.Sprintf("volume: %d", step.want); !strings.Contains(a.View(), header) {
+			t.Errorf("after %d x %q view does not show %q", step.times, step.key, header)
+		}
+		if step.want == 0 {
+			if !a.tracksQueue.volume.Silent {
+				t.Error("volume 0 is not silent")
+			}
+			continue
+		}
+		gain := math.Pow(a.tracksQueue.volume.Base, a.tracksQueue.volume.Volume)
+		if math.Abs(gain*100-float64(step.want)) > 1e-9 {
+			t.Errorf("volume %d has gain %v", step.want, gain)
+		}
+	}
+}
+
+func queuePaths(q tracksQueue) []string {
+	paths := make([]string, 0, q.len())
+	for _, t := range q.queue {
+		paths = append(paths, t.path)
+	}
+	return paths
+}
+
+func TestInsertTrack(t *testing.T) {
+	tests := []struct {
+		name        string
+		index       int
+		wantPaths   []string
+		wantCurrent int
+		wantEnded   bool
+	}{
+		{"before current", 0, []string{"/music/x.mp3", "/music/a.mp3", "/music/b.mp3", "/music/c.mp3"}, 2, true},
+		{"at current", 1, []string{"/music/a.mp3", "/music/x.mp3", "/music/b.mp3", "/music/c.mp3"}, 2, true},
+		{"after current", 2, []string{"/music/a.mp3", "/music/b.mp3", "/music/x.mp3", "/music/c.mp3"}, 1, false},
+		{"past the end", 10, []string{"/music/a.mp3", "/music/b.mp3", "/music/c.mp3", "/music/x.mp3"}, 1, false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			a := testState(t, "/music/a.mp3", "/music/b.mp3", "/music/c.mp3")
+			q := &a.tracksQueue
+			q.currentTrack = 1
+			q.queue[0].ended = true
+
+			q.insertTrack(test.index, testTrack("/music/x.mp3", int(basicSampleRate)))
+
+			if got := queuePaths(*q); !slices.Equal(got, test.wantPaths) {
+				t.Errorf("queue = %v, want %v", got, test.wantPaths)
+			}
+			if q.currentTrack != test.wantCurrent {
+				t.Errorf("current track = %d, want %d", q.currentTrack, test.wantCurrent)
+			}
+			if current, _ := q.getCurrentTrack(); current.path != "/music/b.mp3" {
+				t.Errorf("current track is %s, want /music/b.mp3", current.path)
+			}
+			inserted := q.queue[q.trackIndex("/music/x.mp3")]
+			if inserted.ended != test.wantEnded {
+				t.Errorf("inserted track ended = %v, want %v", inserted.ended, test.wantEnded)
+			}
+		})
+	}
+}
+
+func TestInsertTrackIntoEmptyQueue(t *testing.T) {
+	a := testState(t)
+	a.tracksQueue.insertTrack(0, testTrack("/music/x.mp3", int(basicSampleRate)))
+	if current, ok := a.tracksQueue.getCurrentTrack(); !ok || current.path != "/music/x.mp3" || current.ended {
+		t.Errorf("current track = %+v, %v, want /music/x.mp3 not ended", current, ok)
+	}
+}
+
+// browser file system backed by a map, paths are absolute
+type fakeFS struct {
+	files fstest.MapFS
+	// errors returned for the paths instead of their contents
+	readErrs map[string]error
+	statErrs map[string]error
+}
+
+func (f fakeFS) name(path string) string {
+	if name := strings.TrimPrefix(filepath.ToSlash(path), "/"); name != "" {
+		return name
+	}
+	return "."
+}
+
+func (f fakeFS) ReadDir(path string) ([]fs.DirEntry, error) {
+	if err := f.readErrs[path]; err != nil {
+		return nil, err
+	}
+	return f.files.ReadDir(f.name(path))
+}
+
+func (f fakeFS) Stat(path string) (fs.FileInfo, error) {
+	if err := f.statErrs[path]; err != nil {
+		return nil, err
+	}
+	return f.files.Stat(f.name(path))
+}
+
+func useFakeFS(t *testing.T) fakeFS {
+	t.Helper()
+	fake := fakeFS{
+		files: fstest.MapFS{
+			"music/album/a.mp3": {},
+			"music/b.mp3":       {},
+		},
+		readErrs: map[string]error{},
+		statErrs: map[string]error{},
+	}
+	prev := browserFS
+	browserFS = fake
+	t.Cleanup(func() { browserFS = prev })
+	return fake
+}
+
+func browse(t *testing.T, dir string) appState {
+	t.Helper()
+	a := testState(t)
+	a.currentDir = dir
+	a, err := a.updateChoices()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return a
+}
+
+func enter(a appState) appState {
+	m, _ := a.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	return m.(appState)
+}
+
+func TestEnterDirectory(t *testing.T) {
+	useFakeFS(t)
+	a := enter(browse(t, "/music"))
+	if a.currentDir != "/music/album" {
+		t.Fatalf("current dir = %s, want /music/album", a.currentDir)
+	}
+	if !slices.Equal(a.choices, []string{"a.mp3"}) {
+		t.Errorf("choices = %v, want [a.mp3]", a.choices)
+	}
+}
+
+func TestNavigationErrorKeepsState(t *testing.T) {
+	tests := []struct {
+		name string
+		dir  string
+		key  string
+		fail func(fakeFS)
+	}{
+		{"directory vanished", "/music", "enter", func(f fakeFS) { f.statErrs["/music/album"] = fs.ErrNotExist }},
+		{"directory unreadable", "/music", "enter", func(f fakeFS) { f.readErrs["/music/album"] = fs.ErrPermission }},
+		{"parent unreadable", "/music/album", "-", func(f fakeFS) { f.readErrs["/music"] = fs.ErrPermission }},
+		{"reload failed", "/music", ".", func(f fakeFS) { f.readErrs["/music"] = fs.ErrPermission }},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			fake := useFakeFS(t)
+			before := browse(t, test.dir)
+			test.fail(fake)
+
+			var a appState
+			if test.key == "enter" {
+				a = enter(before)
+			} else {
+				a = press(before, test.key)
+			}
+
+			if a.tracksQueue.len() != before.tracksQueue.len() {
+				t.Errorf("queue has %d tracks, want %d", a.tracksQueue.len(), before.tracksQueue.len())
+			}
+			if a.currentDir != before.currentDir || !slices.Equal(a.choices, before.choices) {
+				t.Errorf("browser moved to %s %v, want %s %v", a.currentDir, a.choices, before.currentDir, before.choices)
+			}
+			if a.status == "" {
+				t.Error("error is not shown")
+			}
+
+			// the error was transient, so retrying works
+			clear(fake.readErrs)
+			clear(fake.statErrs)
+			if test.key == "enter" {
+				a = enter(a)
+			} else {
+				a = press(a, test.key)
+			}
+			if a.status != "" {
+				t.Errorf("retry failed: %s", a.status)
+			}
+		})
+	}
+}
+
+func TestUppercaseExtension(t *testing.T) {
+	ogg, err := os.ReadFile("testdata/short.ogg")
+	if err != nil {
+		t.Fatal(err)
+	}
+	trackPath := filepath.Join(t.TempDir(), "TRACK.OGG")
+	if err := os.WriteFile(trackPath, ogg, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if !isPlayable(trackPath) {
+		t.Error("file is not shown as playable")
+	}
+	tr, err := loadTrack(trackPath)
+	if err != nil {
+		t.Fatalf("file passes the filter but fails to load: %v", err)
+	}
+	tr.close()
+	for _, name := range []string{"LIST.M3U", "LIST.PLS", "ALBUM.ZIP"} {
+		if !isPlaylist(name) && !isArchive(name) {
+			t.Errorf("%s is not recognized", name)
+		}
+	}
+}
+
+// model whose command panics, like a failing background scan
+type panickingModel struct{}
+
+func (panickingModel) Init() tea.Cmd {
+	return func() tea.Msg { panic("scan failed") }
+}
+func (m panickingModel) Update(tea.Msg) (tea.Model, tea.Cmd) { return m, nil }
+func (panickingModel) View() string                          { return "" }
+
+func TestPanicInCommandReleasesResources(t *testing.T) {
+	prevProgram, prevState := program, lastState
+	t.Cleanup(func() { program, lastState = prevProgram, prevState })
+	lastState = testState(t, "/music/a.mp3")
+	stream := lastState.tracksQueue.queue[0].stream.(*fakeStream)
+	program = tea.NewProgram(panickingModel{},
+		tea.WithInput(nil), tea.WithOutput(io.Discard), tea.WithoutRenderer(), tea.WithoutSignalHandler())
+
+	if _, err := runProgram(); err == nil {
+		t.Error("panic is not reported")
+	}
+	if !stream.closed {
+		t.Error("resources are not released after the panic")
+	}
+}
+
+func TestConsumeWithoutAutoAdvance(t *testing.T) {
+	a := testState(t, "/music/a.mp3", "/music/b.mp3", "/music/c.mp3")
+	q := &a.tracksQueue
+	q.consume, q.autoAdvance, q.stopped = true, false, false
+	finished := q.queue[0].stream.(*fakeStream)
+	// stream of the finished track is played to its end
+	q.ctrl.Streamer = nil
+
+	q.trackEnded()
+
+	if got, want := queuePaths(*q), []string{"/music/b.mp3", "/music/c.mp3"}; !slices.Equal(got, want) {
+		t.Fatalf("queue = %v, want %v", got, want)
+	}
+	if !finished.closed {
+		t.Error("finished track is not closed")
+	}
+	if current, _ := q.getCurrentTrack(); current.path != "/music/b.mp3" || current.ended {
+		t.Errorf("current track = %s, ended %v, want /music/b.mp3 waiting", current.path, current.ended)
+	}
+	if !q.paused() {
+		t.Error("playback goes on")
+	}
+	if q.ctrl.Streamer == nil {
+		t.Error("stream is not rebuilt for the next track")
+	}
+
+	// resume plays the waiting track instead of skipping it
+	q.togglePause()
+	if current, _ := q.getCurrentTrack(); q.paused() || current.path != "/music/b.mp3" {
+		t.Errorf("after resume current track = %s, paused %v", current.path, q.paused())
+	}
+}
+
+func TestPlaysCountedWhenTracksStart(t *testing.T) {
+	a := testState(t, "/music/a.mp3", "/music/a.mp3", "/music/b.mp3")
+	q := &a.tracksQueue
+	now := time.Now()
+
+	// pausing and resuming doesn't start the track again
+	q.stats.observe(q.playingPath() != "", now)
+	a = press(a, "p")
+	a.tracksQueue.stats.observe(a.tracksQueue.playingPath() != "", now)
+	a = press(a, "p")
+	q = &a.tracksQueue
+	q.stats.observe(q.playingPath() != "", now)
+	if got := q.stats.plays["/music/a.mp3"]; got != 1 {
+		t.Fatalf("a played %d times after resume, want 1", got)
+	}
+
+	// the same file queued twice in a row is played twice
+	q.trackEnded()
+	if got := q.stats.plays["/music/a.mp3"]; got != 2 {
+		t.Fatalf("a played %d times, want 2", got)
+	}
+	q.trackEnded()
+	q.repeatOne = true
+	q.trackEnded()
+	if got := q.stats.plays["/music/b.mp3"]; got != 2 {
+		t.Fatalf("b played %d times with repeat, want 2", got)
+	}
+}
+
+func TestSortByTrackNumberPutsUnnumberedLast(t *testing.T) {
+	a := testState(t, "/music/current.mp3")
+	q := &a.tracksQueue
+	for _, tr := range []struct {
+		name   string
+		number int
+	}{{"bonus.mp3", 0}, {"second.mp3", 2}, {"first.mp3", 1}} {
+		queued := testTrack("/music/album/"+tr.name, 100)
+		queued.trackNum = tr.number
+		q.addTrack(queued)
+	}
+	q.sortByTrackNumber()
+	want := []string{"/music/current.mp3", "/music/album/first.mp3", "/music/album/second.mp3", "/music/album/bonus.mp3"}
+	if got := queuePaths(*q); !slices.Equal(got, want) {
+		t.Errorf("queue %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,39 @@
+package main
+
+import tea "github.com/charmbracelet/bubbletea"
+
+func (a appState) updateMouse(msg tea.MouseMsg) appState {
+	if a.showHelp || msg.Action != tea.MouseActionPress {
+		return a
+	}
+	switch {
+	case msg.Button == tea.MouseButtonLeft && msg.Y == a.progressBarRow():
+		if err := a.seekToColumn(msg.X); err != nil {
+			a.status = err.Error()
+		}
+	// wheel moves cursor like j and k
+	case msg.Button == tea.MouseButtonWheelUp:
+		a = a.scroll(-1)
+	case msg.Button == tea.MouseButtonWheelDown:
+		a = a.scroll(1)
+	}
+	return a
+}
+
+// moves cursor of the pane that has focus, keys pick the pane the same way
+func (a appState) scroll(delta int) appState {
+	switch {
+	case a.queueFocus:
+		a.queueCursor = scrolled(a.queueCursor, delta, a.tracksQueue.len())
+	case a.albumView:
+		a.albumCursor = scrolled(a.albumCursor, delta, len(a.albumRows()))
+	default:
+		a.cursor = scrolled(a.cursor, delta, len(a.choices))
+	}
+	return a
+}
+
+// cursor moved by delta, staying inside the list of n entries
+func scrolled(cursor, delta, n int) int {
+	return max(0, min(cursor+delta, n-1))
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// lines of the view as a terminal of the width shows them
+func screenLines(view string, width int) []string {
+	lines := []string{}
+	for _, line := range strings.Split(view, "\n") {
+		runes := []rune(line)
+		for len(runes) > width {
+			lines = append(lines, string(runes[:width]))
+			runes = runes[width:]
+		}
+		lines = append(lines, string(runes))
+	}
+	return lines
+}
+
+func click(a appState, x, y int) appState {
+	m, _ := a.Update(tea.MouseMsg{X: x, Y: y, Button: tea.MouseButtonLeft, Action: tea.MouseActionPress})
+	return m.(appState)
+}
+
+func TestClickProgressBarBelowHeader(t *testing.T) {
+	tests := []struct {
+		name  string
+		width int
+		setup func(*appState)
+	}{
+		{"plain header", 120, func(a *appState) {}},
+		{"queue badge", 120, func(a *appState) { a.config.QueueBadge = true }},
+		{"wrapped header", 30, func(a *appState) {
+			a.config.QueueBadge = true
+			a.tracksQueue.repeatOne = true
+			a.tracksQueue.consume = true
+		}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			a := testState(t, "/music/a long name of the first track.mp3", "/music/b.mp3")
+			a.width = test.width
+			test.setup(&a)
+			stream := a.tracksQueue.queue[0].stream.(*fakeStream)
+
+			row := -1
+			for i, line := range screenLines(a.View(), a.width) {
+				if strings.HasPrefix(line, "[>") {
+					row = i
+					break
+				}
+			}
+			if row == -1 {
+				t.Fatalf("no progress bar in the view:\n%s", a.View())
+			}
+			if got := a.progressBarRow(); got != row {
+				t.Fatalf("progress bar row = %d, view draws it at %d", got, row)
+			}
+			// other rows don't seek
+			a = click(a, 10, row+1)
+			if stream.pos != 0 {
+				t.Fatalf("click below the bar seeked to %d", stream.pos)
+			}
+			a = click(a, 10, row)
+			if stream.pos == 0 {
+				t.Error("click on the bar did not seek")
+			}
+		})
+	}
+}
+
+func wheel(a appState, button tea.MouseButton) appState {
+	m, _ := a.Update(tea.MouseMsg{Button: button, Action: tea.MouseActionPress})
+	return m.(appState)
+}
+
+func TestWheelScrollsFocusedPane(t *testing.T) {
+	a := testState(t, "/music/a.mp3", "/music/b.mp3", "/music/c.mp3")
+	a.choices = []string{"x.mp3", "y.mp3"}
+	a.albums = []album{{}, {}, {}, {}}
+
+	a = wheel(a, tea.MouseButtonWheelDown)
+	if a.cursor != 1 {
+		t.Errorf("browser cursor = %d, want 1", a.cursor)
+	}
+
+	a.albumView = true
+	a = wheel(wheel(a, tea.MouseButtonWheelDown), tea.MouseButtonWheelDown)
+	if a.albumCursor != 2 || a.cursor != 1 {
+		t.Errorf("album cursor = %d, browser cursor = %d, want 2, 1", a.albumCursor, a.cursor)
+	}
+
+	a.showQueue, a.queueFocus = true, true
+	for range 5 {
+		a = wheel(a, tea.MouseButtonWheelDown)
+	}
+	if a.queueCursor != 2 || a.albumCursor != 2 {
+		t.Errorf("queue cursor = %d, album cursor = %d, want 2, 2", a.queueCursor, a.albumCursor)
+	}
+	a = wheel(a, tea.MouseButtonWheelUp)
+	if a.queueCursor != 1 {
+		t.Errorf("queue cursor = %d, want 1", a.queueCursor)
+	}
+
+	a.showQueue, a.queueFocus, a.albumView = false, false, false
+	for range 3 {
+		a = wheel(a, tea.MouseButtonWheelUp)
+	}
+	if a.cursor != 0 {
+		t.Errorf("browser cursor = %d, want 0", a.cursor)
+	}
+}
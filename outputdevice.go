@@ -0,0 +1,84 @@
+package main
+
+import (
+	"bufio"
+	"log"
+	"os/exec"
+	"runtime"
+	"strings"
+)
+
+// sent when default audio output or its port changes, for example
+// when headphones are unplugged
+type outputChangedMsg struct {
+	device string
+}
+
+// returns default PulseAudio or PipeWire sink with its active port
+func outputDevice() (string, error) {
+	out, err := exec.Command("pactl", "get-default-sink").Output()
+	if err != nil {
+		return "", err
+	}
+	sink := strings.TrimSpace(string(out))
+	out, err = exec.Command("pactl", "list", "sinks").Output()
+	if err != nil {
+		return "", err
+	}
+	name, port := "", ""
+	for _, line := range strings.Split(string(out), "\n") {
+		line = strings.TrimSpace(line)
+		if value, ok := strings.CutPrefix(line, "Name: "); ok {
+			name = value
+		}
+		if value, ok := strings.CutPrefix(line, "Active Port: "); ok && name == sink {
+			port = value
+		}
+	}
+	return sink + " " + port, nil
+}
+
+// watches audio server events and reports changes of the output device.
+// returns nil where it is not supported, the process has to be killed on exit
+func watchOutputDevice() *exec.Cmd {
+	if runtime.GOOS != "linux" {
+		return nil
+	}
+	if _, err := exec.LookPath("pactl"); err != nil {
+		log.Println("pause on unplug is disabled: pactl is not found")
+		return nil
+	}
+	last, err := outputDevice()
+	if err != nil {
+		log.Printf("pause on unplug is disabled: %v", err)
+		return nil
+	}
+	cmd := exec.Command("pactl", "subscribe")
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		log.Printf("pause on unplug is disabled: %v", err)
+		return nil
+	}
+	if err := cmd.Start(); err != nil {
+		log.Printf("pause on unplug is disabled: %v", err)
+		return nil
+	}
+	go func() {
+		scanner := bufio.NewScanner(stdout)
+		for scanner.Scan() {
+			// default sink changes are server events, port changes are sink events
+			line := scanner.Text()
+			if !strings.Contains(line, "on sink") && !strings.Contains(line, "on server") {
+				continue
+			}
+			device, err := outputDevice()
+			if err != nil || device == last {
+				continue
+			}
+			log.Printf("audio output changed from %q to %q", last, device)
+			last = device
+			program.Send(outputChangedMsg{device: device})
+		}
+	}()
+	return cmd
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"slices"
+	"strings"
+)
+
+var errUnterminatedQuote = errors.New("unterminated quote")
+
+// splits text into words the way a POSIX shell does: words are separated by
+// spaces, quotes and backslashes escape them. terminals paste dropped files this way
+func splitShellWords(text string) ([]string, error) {
+	words := []string{}
+	var word strings.Builder
+	// word may be empty, for example ''
+	inWord := false
+	var quote rune
+	escaped := false
+	for _, r := range text {
+		switch {
+		case escaped:
+			word.WriteRune(r)
+			escaped = false
+		case quote == '\'':
+			if r == '\'' {
+				quote = 0
+			} else {
+				word.WriteRune(r)
+			}
+		case r == '\\' && (quote == 0 || quote == '"'):
+			escaped = true
+			inWord = true
+		case quote == '"':
+			if r == '"' {
+				quote = 0
+			} else {
+				word.WriteRune(r)
+			}
+		case r == '\'' || r == '"':
+			quote = r
+			inWord = true
+		case r == ' ' || r == '\t' || r == '\n' || r == '\r':
+			if inWord {
+				words = append(words, word.String())
+				word.Reset()
+				inWord = false
+			}
+		default:
+			word.WriteRune(r)
+			inWord = true
+		}
+	}
+	if quote != 0 || escaped {
+		return nil, errUnterminatedQuote
+	}
+	if inWord {
+		words = append(words, word.String())
+	}
+	return words, nil
+}
+
+// some terminals paste dropped files as file:// URLs
+func pastedPath(word string) string {
+	if !strings.HasPrefix(word, "file://") {
+		return word
+	}
+	u, err := url.Parse(word)
+	if err != nil || u.Path == "" {
+		return word
+	}
+	return u.Path
+}
+
+// queues every file and directory of the pasted text
+func (a appState) addPastedPaths(text string) appState {
+	words, err := splitShellWords(text)
+	if err != nil {
+		a.status = err.Error()
+		return a
+	}
+	words = slices.DeleteFunc(words, func(word string) bool { return word == "" })
+	if len(words) == 0 {
+		return a
+	}
+	added := 0
+	for _, word := range words {
+		path, err := a.resolvePath(pastedPath(word))
+		if err == nil {
+			a.status, err = a.tracksQueue.addPath(path)
+		}
+		if err != nil {
+			a.status = err.Error()
+			continue
+		}
+		added++
+	}
+	if len(words) > 1 {
+		a.status = fmt.Sprintf("added %d of %d paths", added, len(words))
+	}
+	if added != 0 {
+		a.tracksQueue.play()
+	}
+	return a
+}
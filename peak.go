@@ -0,0 +1,64 @@
+package main
+
+import (
+	"math"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// peak level given to silent tracks, log of zero peak is -Inf. quieter than
+// the noise floor of 16-bit audio, so a real peak is never that low
+const silentPeakDBFS = -120.0
+
+// result of the peak scan of the file
+type peakScannedMsg struct {
+	path string
+	// highest absolute sample, 1 is full scale
+	peak float64
+	err  error
+}
+
+// decodes the file separately from playback and finds its highest sample
+func scanPeak(path string, format string) (float64, error) {
+	f, err := openTrackFile(path)
+	if err != nil {
+		return 0, err
+	}
+	stream, _, err := decodeTrack(f, format)
+	if err != nil {
+		// decoders don't close the file when they fail
+		f.Close()
+		return 0, err
+	}
+	defer stream.Close()
+	peak := 0.0
+	buf := make([][2]float64, 4096)
+	for {
+		n, ok := stream.Stream(buf)
+		for _, sample := range buf[:n] {
+			peak = max(peak, math.Abs(sample[0]), math.Abs(sample[1]))
+		}
+		if !ok {
+			break
+		}
+	}
+	return peak, stream.Err()
+}
+
+// scans the audio file of the track, the result is stored under the track path
+func scanPeakCmd(t track) tea.Cmd {
+	return func() tea.Msg {
+		peak, err := scanPeak(t.audioPath, t.audioFormat)
+		return peakScannedMsg{path: t.path, peak: peak, err: err}
+	}
+}
+
+// stores the scanned peak in every queued instance of the file
+func (s *tracksQueue) setPeak(path string, peak float64) {
+	for i := range s.queue {
+		if s.queue[i].path == path {
+			s.queue[i].peakDBFS = max(20*math.Log10(peak), silentPeakDBFS)
+			s.queue[i].peakScanned = true
+		}
+	}
+}
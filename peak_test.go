@@ -0,0 +1,40 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSetPeakOfSilentTrack(t *testing.T) {
+	a := testState(t, "/music/a.mp3")
+	a.tracksQueue.setPeak("/music/a.mp3", 0)
+
+	tr := a.tracksQueue.queue[0]
+	if tr.peakDBFS != silentPeakDBFS {
+		t.Errorf("peak = %v dBFS, want %v", tr.peakDBFS, silentPeakDBFS)
+	}
+	if info := tr.info(); !strings.Contains(info, "silent") || strings.Contains(info, "Inf") {
+		t.Errorf("info = %q, want silent", info)
+	}
+}
+
+func TestSetPeak(t *testing.T) {
+	a := testState(t, "/music/a.mp3")
+	a.tracksQueue.setPeak("/music/a.mp3", 0.5)
+
+	if info := a.tracksQueue.queue[0].info(); !strings.Contains(info, "peak -6.0 dBFS") {
+		t.Errorf("info = %q, want peak -6.0 dBFS", info)
+	}
+}
+
+func TestScanPeakOfTruncatedFile(t *testing.T) {
+	before := openFiles(t)
+	for range 20 {
+		if _, err := scanPeak("testdata/truncated.mp3", "mp3"); err == nil {
+			t.Fatal("truncated file scanned without error")
+		}
+	}
+	if after := openFiles(t); after > before {
+		t.Errorf("%d files left open by failed scans", after-before)
+	}
+}
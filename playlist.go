@@ -0,0 +1,271 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// playlist that is preferred when directory has several of them
+const dirPlaylistName = ".gomusic.m3u"
+
+// directive of the saved playlist with gain of the track on the next line,
+// other players skip it as a comment
+const m3uGainDirective = "#EXTGOMUSIC-GAIN:"
+
+// track of the playlist
+type playlistEntry struct {
+	path string
+	// gain in decibels saved with the track, 0 if none
+	gain float64
+}
+
+// writes tracks as a plain M3U playlist, gain of the tracks is kept in directives
+func writeM3U(playlistPath string, tracks []track) error {
+	f, err := os.Create(playlistPath)
+	if err != nil {
+		return err
+	}
+	w := bufio.NewWriter(f)
+	for _, track := range tracks {
+		if track.gain != 0 {
+			w.WriteString(m3uGainDirective + strconv.FormatFloat(track.gain, 'f', -1, 64) + "\n")
+		}
+		w.WriteString(track.path + "\n")
+	}
+	if err := w.Flush(); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// writes queue as extended M3U playlist with durations and titles of tracks
+func (s *tracksQueue) exportExtendedM3U(playlistPath string) error {
+	f, err := os.Create(playlistPath)
+	if err != nil {
+		return err
+	}
+	w := bufio.NewWriter(f)
+	w.WriteString("#EXTM3U\n")
+	for _, track := range s.getTracks() {
+		seconds := int(track.format.SampleRate.D(track.stream.Len()).Round(time.Second).Seconds())
+		fmt.Fprintf(w, "#EXTINF:%d,%s\n%s\n", seconds, strings.ReplaceAll(track.displayName(), "\n", " "), track.path)
+	}
+	if err := w.Flush(); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+func isPlaylist(name string) bool {
+	format := fileFormat(name)
+	return format == "m3u" || format == "m3u8" || format == "pls"
+}
+
+// stream addresses are kept as they are, unlike paths
+func isURL(entry string) bool {
+	scheme, _, ok := strings.Cut(entry, "://")
+	return ok && scheme != "" && !strings.ContainsAny(scheme, `/\`)
+}
+
+// reads entries of M3U or PLS playlist depending on its extension
+func readPlaylist(playlistPath string) ([]playlistEntry, error) {
+	if fileFormat(playlistPath) == "pls" {
+		return readPLS(playlistPath)
+	}
+	return readM3U(playlistPath)
+}
+
+// reads FileN entries of the INI-style PLS playlist in order of N
+func readPLS(playlistPath string) ([]playlistEntry, error) {
+	f, err := os.Open(playlistPath)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	type entry struct {
+		number int
+		path   string
+	}
+	entries := []entry{}
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		key, value, ok := strings.Cut(strings.TrimSpace(scanner.Text()), "=")
+		if !ok || len(key) <= 4 || !strings.EqualFold(key[:4], "file") {
+			continue
+		}
+		number, err := strconv.Atoi(key[4:])
+		if err != nil {
+			continue
+		}
+		value = strings.TrimSpace(value)
+		if !isURL(value) && !filepath.IsAbs(value) {
+			value = filepath.Join(filepath.Dir(playlistPath), value)
+		}
+		entries = append(entries, entry{number, value})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	slices.SortStableFunc(entries, func(a, b entry) int {
+		return a.number - b.number
+	})
+	paths := make([]playlistEntry, len(entries))
+	for i, e := range entries {
+		paths[i] = playlistEntry{path: e.path}
+	}
+	return paths, nil
+}
+
+// reads paths of the M3U playlist. relative paths are relative to the playlist
+func readM3U(playlistPath string) ([]playlistEntry, error) {
+	f, err := os.Open(playlistPath)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	entries := []playlistEntry{}
+	// gain directive applies to the path that follows it
+	gain := 0.0
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(strings.TrimPrefix(scanner.Text(), "\ufeff"))
+		if value, ok := strings.CutPrefix(line, m3uGainDirective); ok {
+			if db, err := strconv.ParseFloat(strings.TrimSpace(value), 64); err == nil {
+				gain = db
+			}
+			continue
+		}
+		// lines starting with # are comments and extended M3U directives
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if !isURL(line) && !filepath.IsAbs(line) {
+			line = filepath.Join(filepath.Dir(playlistPath), line)
+		}
+		entries = append(entries, playlistEntry{path: line, gain: gain})
+		gain = 0
+	}
+	return entries, scanner.Err()
+}
+
+// returns playlist of the directory: .gomusic.m3u or the only M3U or PLS playlist in it
+func findDirPlaylist(dirPath string) string {
+	entries, err := readDir(dirPath)
+	if err != nil {
+		return ""
+	}
+	found := ""
+	for _, entry := range entries {
+		if entry.IsDir() || !isPlaylist(entry.Name()) {
+			continue
+		}
+		if entry.Name() == dirPlaylistName {
+			return filepath.Join(dirPath, entry.Name())
+		}
+		if found != "" {
+			return ""
+		}
+		found = filepath.Join(dirPath, entry.Name())
+	}
+	return found
+}
+
+// queues tracks of the playlist in its order
+func (s *tracksQueue) addPlaylist(playlistPath string) (addSummary, error) {
+	summary := addSummary{}
+	entries, err := readPlaylist(playlistPath)
+	if err != nil {
+		return summary, err
+	}
+	for _, entry := range entries {
+		if isURL(entry.path) {
+			log.Printf("skipped %s: streams are not supported", entry.path)
+			summary.skipped++
+			continue
+		}
+		queued := summary.queued
+		s.addFile(entry.path, &summary)
+		// queued track is the last one
+		if summary.queued != queued && entry.gain != 0 {
+			s.setTrackGain(s.len()-1, entry.gain)
+		}
+	}
+	return summary, nil
+}
+
+// loads playlist of the current directory if nothing is queued,
+// or asks for it depending on the config
+func (a appState) offerDirPlaylist() appState {
+	a.pendingPlaylist = ""
+	if a.tracksQueue.len() != 0 || a.config.PlaylistAutoload == playlistAutoloadOff {
+		return a
+	}
+	playlistPath := findDirPlaylist(a.currentDir)
+	if playlistPath == "" {
+		return a
+	}
+	if a.config.PlaylistAutoload == playlistAutoloadAsk {
+		a.pendingPlaylist = playlistPath
+		a.status = "press P to load playlist " + filepath.Base(playlistPath)
+		return a
+	}
+	return a.loadPlaylist(playlistPath)
+}
+
+func (a appState) loadPlaylist(playlistPath string) appState {
+	summary, err := a.tracksQueue.addPlaylist(playlistPath)
+	if err != nil {
+		a.status = err.Error()
+		return a
+	}
+	a.tracksQueue.play()
+	a.status = filepath.Base(playlistPath) + ": " + summary.String()
+	return a
+}
+
+// loads every track of the playlist and writes a line about each of them.
+// streams are listed but not checked.
+// returns false if any track can't be played
+func validatePlaylist(w io.Writer, playlistPath string) (bool, error) {
+	entries, err := readPlaylist(playlistPath)
+	if err != nil {
+		return false, err
+	}
+	bad := 0
+	for i, entry := range entries {
+		trackPath := entry.path
+		result := "ok"
+		if isURL(trackPath) {
+			fmt.Fprintf(w, "%d: %s: stream, not checked\n", i+1, trackPath)
+			continue
+		}
+		track, err := loadTrack(trackPath)
+		switch {
+		case err == nil:
+			track.close()
+		case errors.Is(err, os.ErrNotExist):
+			result = "missing"
+		case errors.Is(errFormatUnsupported, err) || errors.Is(errFileIsNotTrack, err):
+			result = "unsupported"
+		default:
+			result = "error: " + err.Error()
+		}
+		if err != nil {
+			bad++
+		}
+		fmt.Fprintf(w, "%d: %s: %s\n", i+1, trackPath, result)
+	}
+	fmt.Fprintf(w, "%d tracks, %d bad\n", len(entries), bad)
+	return bad == 0, nil
+}
@@ -0,0 +1,37 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestSavedPlaylistKeepsTrackGain(t *testing.T) {
+	dir := t.TempDir()
+	ogg, err := os.ReadFile(filepath.Join("testdata", "short.ogg"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	saved := []track{{path: filepath.Join(dir, "a.ogg"), gain: -3.5}, {path: filepath.Join(dir, "b.ogg")}}
+	for _, tr := range saved {
+		if err := os.WriteFile(tr.path, ogg, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	playlistPath := filepath.Join(dir, "list.m3u")
+	if err := writeM3U(playlistPath, saved); err != nil {
+		t.Fatal(err)
+	}
+
+	q := newTrackQueue()
+	defer q.clear()
+	summary, err := q.addPlaylist(playlistPath)
+	if err != nil || summary.queued != 2 {
+		t.Fatalf("queued %d tracks of the playlist: %v", summary.queued, err)
+	}
+	for i, tr := range q.getTracks() {
+		if tr.gain != saved[i].gain {
+			t.Errorf("%s has gain %v, want %v", filepath.Base(tr.path), tr.gain, saved[i].gain)
+		}
+	}
+}
@@ -0,0 +1,282 @@
+package main
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gopxl/beep/v2"
+)
+
+// how much of each track is decoded ahead of playback, 0 disables it
+var prebufferDuration time.Duration
+
+// samples decoded by the background goroutine at once
+const prebufferChunk = 4096
+
+// plays the resampled track, decoding it ahead in a background goroutine while
+// active, so slow reads (for example from network mounts) don't stall the speaker.
+// only the current and the next tracks are active, others pass samples through.
+// activation, seeking and playback happen under speaker lock
+type prebuffer struct {
+	source beep.Streamer
+	// decoder under the source, it tells position of the track
+	stream beep.StreamSeeker
+	// samples of the stream per sample of the source
+	ratio float64
+	size  int
+	// held while the fill goroutine reads the source and while the stream is
+	// seeked. taken before mu
+	sourceMu sync.Mutex
+	// changed by seek, so samples decoded before it are dropped
+	generation atomic.Int64
+
+	mu   sync.Mutex
+	cond *sync.Cond
+	// ring of decoded samples, allocated while the buffer is used
+	buf   [][2]float64
+	start int
+	count int
+	// goroutine is asked to fill the ring
+	active bool
+	// goroutine runs
+	filling bool
+	// position of the stream after the last decoded sample
+	readPos int
+	// source has no more samples
+	drained bool
+}
+
+func newPrebuffer(source beep.Streamer, stream beep.StreamSeeker, ratio float64, size int) *prebuffer {
+	b := &prebuffer{
+		source: source,
+		stream: stream,
+		ratio:  ratio,
+		size:   max(size, prebufferChunk),
+	}
+	b.cond = sync.NewCond(&b.mu)
+	return b
+}
+
+// starts decoding ahead
+func (b *prebuffer) activate() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.active {
+		return
+	}
+	b.active = true
+	if !b.filling {
+		b.readPos = b.stream.Position()
+	}
+	if b.buf == nil {
+		b.buf = make([][2]float64, b.size)
+	}
+	if !b.filling {
+		b.filling = true
+		go b.fill()
+	}
+}
+
+// stops decoding ahead. samples decoded already are played, then the ring is released
+func (b *prebuffer) deactivate() {
+	b.mu.Lock()
+	b.active = false
+	b.cond.Broadcast()
+	b.mu.Unlock()
+}
+
+// stops decoding ahead, waits for the goroutine and drops decoded samples, so the
+// stream can be closed or played without the buffer. returns the played position
+func (b *prebuffer) stop() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.active = false
+	b.cond.Broadcast()
+	for b.filling {
+		b.cond.Wait()
+	}
+	pos := b.played()
+	b.buf, b.start, b.count = nil, 0, 0
+	return pos
+}
+
+func (b *prebuffer) fill() {
+	chunk := make([][2]float64, prebufferChunk)
+	for {
+		b.mu.Lock()
+		for b.active && (b.drained || len(b.buf)-b.count < len(chunk)) {
+			b.cond.Wait()
+		}
+		if !b.active {
+			b.filling = false
+			b.cond.Broadcast()
+			b.mu.Unlock()
+			return
+		}
+		b.mu.Unlock()
+
+		// source is read without holding mu, so playback continues from the ring
+		b.sourceMu.Lock()
+		generation := b.generation.Load()
+		n, ok := b.source.Stream(chunk)
+		pos := b.stream.Position()
+		b.sourceMu.Unlock()
+
+		b.mu.Lock()
+		if generation == b.generation.Load() {
+			for _, sample := range chunk[:n] {
+				b.buf[(b.start+b.count)%len(b.buf)] = sample
+				b.count++
+			}
+			b.readPos = pos
+			b.drained = !ok
+		}
+		b.cond.Broadcast()
+		b.mu.Unlock()
+	}
+}
+
+func (b *prebuffer) Stream(samples [][2]float64) (int, bool) {
+	b.mu.Lock()
+	// ring ran dry, playback waits for the source as it would without buffering
+	for b.count == 0 && b.filling && !b.drained {
+		b.cond.Wait()
+	}
+	if b.count != 0 {
+		n := min(len(samples), b.count)
+		for i := range samples[:n] {
+			samples[i] = b.buf[b.start]
+			b.start = (b.start + 1) % len(b.buf)
+		}
+		b.count -= n
+		b.cond.Broadcast()
+		b.mu.Unlock()
+		return n, true
+	}
+	if !b.filling {
+		// ring isn't needed until the buffer is activated again
+		b.buf = nil
+	}
+	if b.drained {
+		b.mu.Unlock()
+		return 0, false
+	}
+	// nothing else reads the source now, the goroutine is only started under speaker lock
+	b.mu.Unlock()
+	return b.source.Stream(samples)
+}
+
+func (b *prebuffer) Err() error {
+	return b.source.Err()
+}
+
+// position of the next played sample in the stream, not of the decoded one.
+// doesn't wait for the goroutine, which may be stuck on a slow read
+func (b *prebuffer) position() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.played()
+}
+
+func (b *prebuffer) played() int {
+	if !b.filling && b.count == 0 {
+		return b.stream.Position()
+	}
+	return max(b.readPos-int(float64(b.count)*b.ratio), 0)
+}
+
+// buffer decodes ahead or holds decoded samples
+func (b *prebuffer) used() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.active || b.filling || b.count != 0
+}
+
+// seeks the stream and drops decoded samples
+func (b *prebuffer) seek(p int) error {
+	b.sourceMu.Lock()
+	defer b.sourceMu.Unlock()
+	err := b.stream.Seek(p)
+	b.generation.Add(1)
+	b.mu.Lock()
+	b.start, b.count = 0, 0
+	b.readPos = b.stream.Position()
+	b.drained = false
+	b.cond.Broadcast()
+	b.mu.Unlock()
+	return err
+}
+
+// builds playback of the track from the current position of the stream. the old
+// buffer is replaced, the new one stays active if the old one was
+func (t *track) rebuildPlayback() {
+	active := false
+	if t.buffer != nil {
+		t.buffer.mu.Lock()
+		active = t.buffer.active
+		t.buffer.mu.Unlock()
+		// samples decoded but not played yet are decoded again
+		if pos := t.buffer.stop(); pos != t.stream.Position() && t.seekable() {
+			t.stream.Seek(pos)
+		}
+	}
+	t.buffer = nil
+	t.resampled = t.playback()
+	if prebufferDuration <= 0 {
+		return
+	}
+	ratio := 1.0
+	if t.format.SampleRate != basicSampleRate && !rawOutput {
+		ratio = float64(t.format.SampleRate) / float64(basicSampleRate)
+	}
+	t.buffer = newPrebuffer(t.resampled, t.stream, ratio, basicSampleRate.N(prebufferDuration))
+	t.resampled = t.buffer
+	if active {
+		t.buffer.activate()
+	}
+}
+
+// position of the next played sample of the track
+func (t track) position() int {
+	if t.buffer != nil {
+		return t.buffer.position()
+	}
+	return t.stream.Position()
+}
+
+// seeks the stream of the track, dropping samples decoded ahead
+func (t track) seekStream(pos int) error {
+	if t.buffer != nil {
+		return t.buffer.seek(pos)
+	}
+	return t.stream.Seek(pos)
+}
+
+func (t track) close() error {
+	if t.buffer != nil {
+		t.buffer.stop()
+	}
+	return t.stream.Close()
+}
+
+// decodes ahead only the current track and the one after it, so memory and
+// goroutines don't grow with the queue. called under speaker lock
+func (s *tracksQueue) updatePrebuffers() {
+	next := s.nextIndex()
+	for i, t := range s.queue {
+		switch {
+		case t.buffer == nil:
+		case i == s.currentTrack || i == next:
+			t.buffer.activate()
+		case t.ended && t.buffer.used():
+			// played track isn't decoded when it plays again, its stream goes back
+			// to the sample heard last and the ring is released
+			t.buffer.deactivate()
+			s.queue[i].rebuildPlayback()
+		default:
+			// samples decoded already are played if the track plays
+			t.buffer.deactivate()
+		}
+	}
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+// stream whose every sample holds its own position
+type countingStream struct {
+	fakeStream
+}
+
+func (c *countingStream) Stream(samples [][2]float64) (int, bool) {
+	n := min(len(samples), c.length-c.pos)
+	for i := range samples[:n] {
+		samples[i] = [2]float64{float64(c.pos + i), 0}
+	}
+	c.pos += n
+	return n, n > 0
+}
+
+func usePrebuffer(t *testing.T, d time.Duration) {
+	t.Helper()
+	old := prebufferDuration
+	prebufferDuration = d
+	t.Cleanup(func() { prebufferDuration = old })
+}
+
+func streamFrom(t *testing.T, b *prebuffer, want int) {
+	t.Helper()
+	samples := make([][2]float64, 100)
+	n, ok := b.Stream(samples)
+	if !ok || n == 0 {
+		t.Fatalf("stream ended at %d", want)
+	}
+	if got := int(samples[0][0]); got != want {
+		t.Fatalf("first sample is from %d, want %d", got, want)
+	}
+	if got := b.position(); got != want+n {
+		t.Fatalf("position is %d, want %d", got, want+n)
+	}
+}
+
+func TestPrebufferPlaysStreamInOrderAndSeeks(t *testing.T) {
+	stream := &countingStream{fakeStream{length: 100000}}
+	b := newPrebuffer(stream, stream, 1, 20000)
+	b.activate()
+	defer b.stop()
+
+	streamFrom(t, b, 0)
+	streamFrom(t, b, 100)
+	if err := b.seek(50000); err != nil {
+		t.Fatal(err)
+	}
+	// samples decoded before the seek are dropped
+	streamFrom(t, b, 50000)
+
+	b.deactivate()
+	streamFrom(t, b, 50100)
+}
+
+func TestPrebufferOnlyCurrentAndNextTracks(t *testing.T) {
+	usePrebuffer(t, time.Second)
+	a := testState(t, "/music/a.mp3", "/music/b.mp3", "/music/c.mp3", "/music/d.mp3")
+	q := &a.tracksQueue
+	defer q.clear()
+
+	active := func() []bool {
+		result := make([]bool, q.len())
+		for i, t := range q.queue {
+			result[i] = t.buffer.used()
+		}
+		return result
+	}
+	q.rebuildStreamer()
+	if got := active(); !got[0] || !got[1] || got[2] || got[3] {
+		t.Fatalf("buffers in use %v, want only the first two", got)
+	}
+	q.nextTrack()
+	if got := active(); got[0] || !got[1] || !got[2] || got[3] {
+		t.Fatalf("buffers in use after next track %v, want the second and the third", got)
+	}
+}
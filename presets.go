@@ -0,0 +1,105 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/gopxl/beep/v2/speaker"
+)
+
+// named combination of playback settings. missing settings are left as they are
+type preset struct {
+	Volume      *int  `json:"volume,omitempty"`
+	Loudness    *bool `json:"loudness,omitempty"`
+	RepeatOne   *bool `json:"repeatOne,omitempty"`
+	Consume     *bool `json:"consume,omitempty"`
+	AutoAdvance *bool `json:"autoAdvance,omitempty"`
+}
+
+func (s *tracksQueue) setLoudness(enabled bool) {
+	speaker.Lock()
+	s.loudness.Enabled = enabled
+	speaker.Unlock()
+}
+
+// current settings as a preset
+func (a appState) currentPreset() preset {
+	volume := a.tracksQueue.getVolumePercents()
+	loud := a.tracksQueue.loud()
+	repeatOne := a.tracksQueue.repeatOne
+	consume := a.tracksQueue.consume
+	autoAdvance := a.config.AutoAdvance
+	return preset{
+		Volume:      &volume,
+		Loudness:    &loud,
+		RepeatOne:   &repeatOne,
+		Consume:     &consume,
+		AutoAdvance: &autoAdvance,
+	}
+}
+
+func (a appState) applyPreset(p preset) appState {
+	if p.Volume != nil {
+		a.tracksQueue.setVolumePercent(*p.Volume)
+	}
+	if p.Loudness != nil {
+		a.tracksQueue.setLoudness(*p.Loudness)
+	}
+	if p.RepeatOne != nil {
+		a.tracksQueue.repeatOne = *p.RepeatOne
+		a.tracksQueue.repeats = 0
+	}
+	if p.Consume != nil {
+		a.tracksQueue.consume = *p.Consume
+	}
+	if p.AutoAdvance != nil && *p.AutoAdvance != a.config.AutoAdvance {
+		a.config.AutoAdvance = *p.AutoAdvance
+		a.tracksQueue.setAutoAdvance(a.config.AutoAdvance)
+		if err := a.config.save(); err != nil {
+			a.status = err.Error()
+		}
+	}
+	return a
+}
+
+// runs preset command. without arguments lists presets, "save NAME"
+// stores the current settings, NAME applies the preset
+func (a appState) presetCommand(arg string) appState {
+	if arg == "" {
+		if len(a.config.Presets) == 0 {
+			a.status = "no presets, save one with :preset save NAME"
+			return a
+		}
+		names := make([]string, 0, len(a.config.Presets))
+		for name := range a.config.Presets {
+			names = append(names, name)
+		}
+		slices.Sort(names)
+		a.status = "presets: " + strings.Join(names, ", ")
+		return a
+	}
+	if name, ok := strings.CutPrefix(arg, "save "); ok {
+		name = strings.TrimSpace(name)
+		if a.config.Presets == nil {
+			a.config.Presets = map[string]preset{}
+		}
+		a.config.Presets[name] = a.currentPreset()
+		if err := a.config.save(); err != nil {
+			a.status = err.Error()
+			return a
+		}
+		a.status = fmt.Sprintf("saved preset %q", name)
+		return a
+	}
+	p, ok := a.config.Presets[arg]
+	if !ok {
+		a.status = fmt.Sprintf("no preset %q", arg)
+		return a
+	}
+	a = a.applyPreset(p)
+	if a.status == "" {
+		a.status = fmt.Sprintf("preset %q", arg)
+	}
+	return a
+}
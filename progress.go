@@ -0,0 +1,224 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/gopxl/beep/v2/speaker"
+)
+
+const maxProgressBarWidth = 60
+
+var errNotSeekable = errors.New("track is not seekable")
+
+// A-B loop inside the current track, points are samples of the track stream
+type abLoop struct {
+	// track the loop belongs to
+	path string
+	// number of set points, loop is active when both A and B are set
+	points int
+	start  int
+	end    int
+}
+
+func (l abLoop) active() bool {
+	return l.points == 2
+}
+
+// returns position and length of the current track in samples
+func (s *tracksQueue) position() (int, int, bool) {
+	currentTrack, ok := s.getCurrentTrack()
+	if !ok {
+		return 0, 0, false
+	}
+	speaker.Lock()
+	defer speaker.Unlock()
+	return currentTrack.position(), currentTrack.stream.Len(), true
+}
+
+// live streams and files opened without seeking can only be played forward
+func (t track) seekable() bool {
+	return t.canSeek && t.stream.Len() > 0
+}
+
+// moves current track to the sample position
+func (s *tracksQueue) seek(pos int) error {
+	currentTrack, ok := s.getCurrentTrack()
+	if !ok {
+		return nil
+	}
+	if !currentTrack.seekable() {
+		return errNotSeekable
+	}
+	end := currentTrack.stream.Len() - 1
+	if currentTrack.trimEnd != 0 {
+		end = currentTrack.trimEnd - 1
+	}
+	pos = max(currentTrack.trimStart, min(pos, end))
+	speaker.Lock()
+	err := currentTrack.seekStream(pos)
+	if err == nil && currentTrack.trimEnd != 0 {
+		// trimmed track counts samples left to play, so it has to be rebuilt
+		s.queue[s.currentTrack].rebuildPlayback()
+	}
+	speaker.Unlock()
+	if err != nil {
+		return err
+	}
+	if currentTrack.trimEnd != 0 {
+		s.rebuildStreamer()
+	}
+	return nil
+}
+
+// moves current track to the percentage of its length
+func (s *tracksQueue) seekPercent(p float64) error {
+	currentTrack, ok := s.getCurrentTrack()
+	if !ok {
+		return nil
+	}
+	if !currentTrack.seekable() {
+		return errNotSeekable
+	}
+	return s.seek(int(p / 100 * float64(currentTrack.stream.Len())))
+}
+
+// returns loop of the current track
+func (s *tracksQueue) currentLoop() abLoop {
+	currentTrack, ok := s.getCurrentTrack()
+	if !ok || currentTrack.path != s.loop.path {
+		return abLoop{}
+	}
+	return s.loop
+}
+
+// sets A, then B point of the loop at the current position. third call clears the loop
+func (s *tracksQueue) toggleLoopPoint() error {
+	currentTrack, ok := s.getCurrentTrack()
+	if !ok {
+		return nil
+	}
+	if !currentTrack.seekable() {
+		return errNotSeekable
+	}
+	pos, _, _ := s.position()
+	loop := s.currentLoop()
+	switch loop.points {
+	case 0:
+		s.loop = abLoop{path: currentTrack.path, points: 1, start: pos}
+	case 1:
+		if pos <= loop.start {
+			return nil
+		}
+		s.loop.points = 2
+		s.loop.end = pos
+	default:
+		s.loop = abLoop{}
+	}
+	return nil
+}
+
+// seeks back to A point when playback reaches B point
+func (s *tracksQueue) checkLoop() {
+	loop := s.currentLoop()
+	if !loop.active() {
+		return
+	}
+	currentTrack, _ := s.getCurrentTrack()
+	end := loop.end
+	// trimmed track ends before its stream does
+	if currentTrack.trimEnd != 0 {
+		end = min(end, currentTrack.trimEnd-1)
+	}
+	if pos, _, _ := s.position(); pos >= end {
+		// seek rebuilds trimmed playback, which counts samples left to play
+		s.seek(loop.start)
+	}
+}
+
+// formats duration as m:ss, or as h:mm:ss if it is an hour or longer
+func formatDuration(d time.Duration) string {
+	seconds := int(d.Round(time.Second).Seconds())
+	if seconds >= 3600 {
+		return fmt.Sprintf("%d:%02d:%02d", seconds/3600, seconds/60%60, seconds%60)
+	}
+	return fmt.Sprintf("%d:%02d", seconds/60, seconds%60)
+}
+
+// renders progress of the current track. loop region is drawn with '~'
+// between 'A' and 'B' marks
+func (a appState) progressLine() string {
+	pos, length, ok := a.tracksQueue.position()
+	if !ok {
+		return ""
+	}
+	currentTrack, _ := a.tracksQueue.getCurrentTrack()
+	sampleRate := currentTrack.format.SampleRate
+	// length is unknown or bar can't be used for seeking
+	if !currentTrack.seekable() {
+		return formatDuration(sampleRate.D(pos)) + " (not seekable)"
+	}
+	times := fmt.Sprintf(" %s / %s", formatDuration(sampleRate.D(pos)), formatDuration(sampleRate.D(length)))
+	width := maxProgressBarWidth
+	if a.width != 0 {
+		width = min(width, a.width-len(times)-2)
+	}
+	if width <= 0 {
+		return strings.TrimSpace(times)
+	}
+	cell := func(sample int) int {
+		return min(sample*width/length, width-1)
+	}
+	bar := make([]rune, width)
+	for i := range bar {
+		bar[i] = '-'
+		if i < cell(pos) {
+			bar[i] = '='
+		}
+	}
+	loop := a.tracksQueue.currentLoop()
+	if loop.active() {
+		for i := cell(loop.start); i <= cell(loop.end); i++ {
+			bar[i] = '~'
+		}
+	}
+	if loop.points != 0 {
+		bar[cell(loop.start)] = 'A'
+	}
+	if loop.active() {
+		bar[cell(loop.end)] = 'B'
+	}
+	bar[cell(pos)] = '>'
+	return "[" + string(bar) + "]" + times
+}
+
+// line of the screen the progress bar is drawn on, right below the header
+func (a appState) progressBarRow() int {
+	return screenRows(a.headerView(), a.width)
+}
+
+// number of screen lines the text takes, lines longer than width wrap
+func screenRows(text string, width int) int {
+	rows := 0
+	for _, line := range strings.Split(text, "\n") {
+		rows++
+		if w := lipgloss.Width(line); width > 0 && w > width {
+			rows += (w - 1) / width
+		}
+	}
+	return rows
+}
+
+// seeks to the position of the progress bar at the column of the screen
+func (a appState) seekToColumn(x int) error {
+	line := a.progressLine()
+	end := strings.IndexByte(line, ']')
+	if !strings.HasPrefix(line, "[") || x < 1 || x >= end {
+		return nil
+	}
+	// middle of the clicked cell
+	return a.tracksQueue.seekPercent((float64(x-1) + 0.5) / float64(end-1) * 100)
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestFormatDuration(t *testing.T) {
+	tests := []struct {
+		d    time.Duration
+		want string
+	}{
+		{0, "0:00"},
+		{7 * time.Second, "0:07"},
+		{59*time.Second + 400*time.Millisecond, "0:59"},
+		{59*time.Second + 600*time.Millisecond, "1:00"},
+		{3*time.Minute + 5*time.Second, "3:05"},
+		{59*time.Minute + 59*time.Second, "59:59"},
+		{time.Hour, "1:00:00"},
+		{time.Hour + 2*time.Minute + 3*time.Second, "1:02:03"},
+		{12*time.Hour + 34*time.Minute + 56*time.Second, "12:34:56"},
+	}
+	for _, test := range tests {
+		if got := formatDuration(test.d); got != test.want {
+			t.Errorf("formatDuration(%v) = %q, want %q", test.d, got, test.want)
+		}
+	}
+}
+
+func TestLoopInsideTrimmedTrack(t *testing.T) {
+	a := testState(t)
+	tr := testTrack("/music/a.mp3", 10000)
+	tr.trimStart, tr.trimEnd = 1000, 8000
+	tr.stream.Seek(tr.trimStart)
+	tr.resampled = tr.playback()
+	a.tracksQueue.addTrack(tr)
+	q := &a.tracksQueue
+	q.loop = abLoop{path: tr.path, points: 2, start: 2000, end: 5000}
+
+	q.seek(5500)
+	q.checkLoop()
+
+	if pos, _, _ := q.position(); pos != 2000 {
+		t.Fatalf("position = %d, want loop start 2000", pos)
+	}
+	// after the jump back the track still plays up to the trimmed end
+	if n := streamedSamples(q.queue[0].resampled); n != 6000 {
+		t.Errorf("played %d samples after the jump, want 6000", n)
+	}
+}
+
+func TestLoopEndPastTrimmedEnd(t *testing.T) {
+	a := testState(t)
+	tr := testTrack("/music/a.mp3", 10000)
+	tr.trimEnd = 8000
+	tr.resampled = tr.playback()
+	a.tracksQueue.addTrack(tr)
+	q := &a.tracksQueue
+	q.loop = abLoop{path: tr.path, points: 2, start: 2000, end: 9000}
+
+	q.seek(7999)
+	q.checkLoop()
+
+	if pos, _, _ := q.position(); pos != 2000 {
+		t.Errorf("position = %d, want loop start 2000", pos)
+	}
+}
@@ -0,0 +1,151 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/gopxl/beep/v2/speaker"
+)
+
+// terminals narrower than this show the queue below the browser
+const minSplitWidth = 80
+
+// removes track at the index. playback moves to the next track
+// if the current one is removed
+func (s *tracksQueue) removeTrackAt(index int) {
+	if index < 0 || index >= s.len() {
+		return
+	}
+	removed := s.queue[index]
+	wasCurrent := index == s.currentTrack
+	s.queue = slices.Delete(s.queue, index, index+1)
+	switch {
+	case s.len() == 0:
+		speaker.Lock()
+		s.ctrl.Streamer = nil
+		speaker.Unlock()
+		s.currentTrack = 0
+	case index < s.currentTrack:
+		s.currentTrack--
+	case wasCurrent && index == s.len():
+		// the last track was removed, nothing is left to play
+		s.currentTrack--
+	}
+	if s.len() != 0 {
+		s.rebuildStreamer()
+	}
+	// speaker reads the track no more, its stream can be closed
+	removed.close()
+	switch {
+	case s.len() == 0 || wasCurrent && index == s.len():
+		s.stopped = true
+		// samples of the removed track are buffered already
+		output.Clear()
+	case wasCurrent:
+		s.play()
+	}
+}
+
+// swaps track at the index with the track at index+delta. tracks that
+// end up before the current one are treated as played
+func (s *tracksQueue) moveTrack(index int, delta int) bool {
+	other := index + delta
+	if index < 0 || index >= s.len() || other < 0 || other >= s.len() {
+		return false
+	}
+	s.queue[index], s.queue[other] = s.queue[other], s.queue[index]
+	switch s.currentTrack {
+	case index:
+		s.currentTrack = other
+	case other:
+		s.currentTrack = index
+	}
+	for _, i := range []int{index, other} {
+		if i == s.currentTrack {
+			continue
+		}
+		played := i < s.currentTrack
+		if !played && s.queue[i].ended {
+			s.rewindTrack(i)
+		}
+		s.queue[i].ended = played
+	}
+	s.rebuildStreamer()
+	return true
+}
+
+// handles keys of the focused queue pane. returns false for keys
+// that work like in the browser
+func (a appState) updateQueuePane(msg tea.KeyMsg) (appState, bool) {
+	switch msg.String() {
+	case "up", "k":
+		if a.queueCursor > 0 {
+			a.queueCursor--
+		} else if a.config.WrapCursor {
+			a.queueCursor = max(a.tracksQueue.len()-1, 0)
+		}
+	case "down", "j":
+		if a.queueCursor < a.tracksQueue.len()-1 {
+			a.queueCursor++
+		} else if a.config.WrapCursor {
+			a.queueCursor = 0
+		}
+	case "K", "J":
+		delta := 1
+		if msg.String() == "K" {
+			delta = -1
+		}
+		if a.tracksQueue.moveTrack(a.queueCursor, delta) {
+			a.queueCursor += delta
+		}
+	case "d":
+		a.tracksQueue.removeTrackAt(a.queueCursor)
+		a.queueCursor = max(0, min(a.queueCursor, a.tracksQueue.len()-1))
+	case "enter":
+		a.tracksQueue.goToTrack(a.queueCursor)
+	default:
+		return a, slices.Contains(browserKeys, msg.String())
+	}
+	return a, true
+}
+
+// renders queue around the cursor
+func (a appState) queuePaneView() string {
+	s := "queue:\n"
+	tracks := a.tracksQueue.getTracks()
+	if len(tracks) == 0 {
+		return s + "  empty\n"
+	}
+	start := max(0, min(a.queueCursor-a.listSize/2, len(tracks)-a.listSize))
+	end := min(len(tracks), start+a.listSize)
+	for i := start; i < end; i++ {
+		cursor := " "
+		if a.queueFocus && i == a.queueCursor {
+			cursor = cursorStyle.Render(">")
+		}
+		name := tracks[i].displayName()
+		switch {
+		case i == a.tracksQueue.getCurrentTrackIndex():
+			name = playingStyle.Render(name)
+		case tracks[i].ended:
+			name = dimStyle.Render(name)
+		}
+		s += fmt.Sprintf("%s %d. %s\n", cursor, i+1, name)
+	}
+	return s
+}
+
+// shows list of the browser and the queue side by side,
+// or one under another on narrow terminals
+func (a appState) splitView(list string) string {
+	queue := a.queuePaneView()
+	if a.width < minSplitWidth {
+		return list + "\n" + queue
+	}
+	half := a.width / 2
+	left := lipgloss.NewStyle().Width(half).Render(lipgloss.NewStyle().MaxWidth(half - 1).Render(list))
+	right := lipgloss.NewStyle().MaxWidth(a.width - half).Render(queue)
+	return lipgloss.JoinHorizontal(lipgloss.Top, left, right) + "\n"
+}
@@ -0,0 +1,67 @@
+package main
+
+import "testing"
+
+func TestRemovePlayingTrackStopsPlayback(t *testing.T) {
+	out := useStubOutput(t)
+	a := testState(t, "/music/a.mp3")
+	stream := a.tracksQueue.queue[0].stream.(*fakeStream)
+
+	a.tracksQueue.removeTrack("/music/a.mp3")
+
+	q := a.tracksQueue
+	if q.len() != 0 {
+		t.Fatalf("queue length = %d, want 0", q.len())
+	}
+	if q.ctrl.Streamer != nil {
+		t.Error("streamer still set after the queue became empty")
+	}
+	if !q.stopped {
+		t.Error("queue not stopped")
+	}
+	if !stream.closed {
+		t.Error("stream of the removed track not closed")
+	}
+	if out.clears == 0 {
+		t.Error("speaker not cleared")
+	}
+}
+
+func TestRemoveLastPlayingTrackStopsPlayback(t *testing.T) {
+	out := useStubOutput(t)
+	a := testState(t, "/music/a.mp3", "/music/b.mp3")
+	a.tracksQueue.currentTrack = 1
+	a.tracksQueue.queue[0].ended = true
+
+	a.tracksQueue.removeTrack("/music/b.mp3")
+
+	q := a.tracksQueue
+	if q.len() != 1 || q.currentTrack != 0 {
+		t.Fatalf("queue length = %d, current = %d, want 1, 0", q.len(), q.currentTrack)
+	}
+	if !q.stopped {
+		t.Error("queue not stopped")
+	}
+	if out.clears == 0 {
+		t.Error("speaker not cleared")
+	}
+}
+
+func TestRemoveQueuedTrackKeepsPlaying(t *testing.T) {
+	out := useStubOutput(t)
+	a := testState(t, "/music/a.mp3", "/music/b.mp3")
+	stream := a.tracksQueue.queue[1].stream.(*fakeStream)
+
+	a.tracksQueue.removeTrack("/music/b.mp3")
+
+	q := a.tracksQueue
+	if q.len() != 1 || q.stopped {
+		t.Fatalf("queue length = %d, stopped = %v, want 1, false", q.len(), q.stopped)
+	}
+	if !stream.closed {
+		t.Error("stream of the removed track not closed")
+	}
+	if out.clears != 0 {
+		t.Error("speaker cleared while current track kept playing")
+	}
+}
@@ -0,0 +1,186 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+)
+
+// how long client waits for the running instance to answer
+const remoteTimeout = 2 * time.Second
+
+var errNoInstance = errors.New("no running " + executableName + " instance found")
+
+// state of the player reported to remote clients
+type playerStatus struct {
+	Pid      int      `json:"pid"`
+	Playing  string   `json:"playing,omitempty"`
+	Paused   bool     `json:"paused"`
+	Volume   int      `json:"volume"`
+	Position float64  `json:"position"`
+	Duration float64  `json:"duration"`
+	Current  int      `json:"current"`
+	Queue    []string `json:"queue"`
+}
+
+// request sent to the UI, so snapshot is taken on the UI goroutine
+type statusRequest struct {
+	reply chan queueState
+}
+
+// playback command of a remote client, applied by the UI
+type remoteControlMsg struct {
+	command string
+}
+
+// commands that control playback, handled by applyRemote
+var remoteControls = []string{"pause", "next", "prev"}
+
+func (a appState) applyRemote(command string) appState {
+	switch command {
+	case "pause":
+		a.tracksQueue.togglePause()
+	case "next":
+		a.tracksQueue.nextTrack()
+	case "prev":
+		a.tracksQueue.prevTrack()
+	}
+	return a
+}
+
+// copy of the queue state that can be passed to other goroutines.
+// queue is owned by the UI goroutine, so snapshots are taken there
+type queueState struct {
+	tracks   []string
+	current  int
+	playing  string
+	paused   bool
+	volume   int
+	position time.Duration
+	duration time.Duration
+}
+
+// the only way state of the queue is read for remote clients and other integrations
+func (s *tracksQueue) snapshot() queueState {
+	state := queueState{
+		tracks:  make([]string, 0, s.len()),
+		current: s.getCurrentTrackIndex(),
+		paused:  s.paused(),
+		volume:  s.getVolumePercents(),
+	}
+	for _, track := range s.queue {
+		state.tracks = append(state.tracks, track.path)
+	}
+	if currentTrack, ok := s.getCurrentTrack(); ok {
+		// position is read under speaker lock, the speaker moves it
+		pos, length, _ := s.position()
+		state.playing = currentTrack.path
+		state.position = currentTrack.format.SampleRate.D(pos)
+		state.duration = currentTrack.format.SampleRate.D(length)
+	}
+	return state
+}
+
+func (q queueState) playerStatus() playerStatus {
+	return playerStatus{
+		Pid:      os.Getpid(),
+		Playing:  q.playing,
+		Paused:   q.paused,
+		Volume:   q.volume,
+		Position: q.position.Seconds(),
+		Duration: q.duration.Seconds(),
+		Current:  q.current,
+		Queue:    q.tracks,
+	}
+}
+
+// path to the socket used for remote control
+func socketPath() string {
+	runtimeDir := os.Getenv("XDG_RUNTIME_DIR")
+	if !filepath.IsAbs(runtimeDir) {
+		return filepath.Join(os.TempDir(), fmt.Sprintf("%s-%d.sock", executableName, os.Getuid()))
+	}
+	return filepath.Join(runtimeDir, executableName+".sock")
+}
+
+// starts listening for remote clients. returns nil if another instance already listens
+func startRemote() net.Listener {
+	path := socketPath()
+	if conn, err := net.DialTimeout("unix", path, remoteTimeout); err == nil {
+		conn.Close()
+		log.Printf("remote control is disabled, socket %s is used by another instance", path)
+		return nil
+	}
+	// socket left by crashed instance
+	os.Remove(path)
+	listener, err := net.Listen("unix", path)
+	if err != nil {
+		log.Printf("remote control is disabled: %v", err)
+		return nil
+	}
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go serveRemote(conn)
+		}
+	}()
+	return listener
+}
+
+// answers commands of one client, one line per command
+func serveRemote(conn net.Conn) {
+	defer conn.Close()
+	scanner := bufio.NewScanner(conn)
+	encoder := json.NewEncoder(conn)
+	for scanner.Scan() {
+		command := strings.TrimSpace(scanner.Text())
+		switch {
+		case slices.Contains(remoteControls, command):
+			program.Send(remoteControlMsg{command: command})
+			fmt.Fprintln(conn, `{"ok":true}`)
+		case command == "quit":
+			fmt.Fprintln(conn, `{"ok":true}`)
+			program.Send(shutdownMsg{})
+		case command == "status":
+			reply := make(chan queueState, 1)
+			program.Send(statusRequest{reply: reply})
+			select {
+			case state := <-reply:
+				encoder.Encode(state.playerStatus())
+			case <-time.After(remoteTimeout):
+				return
+			}
+		default:
+			fmt.Fprintln(conn, `{"error":"unknown command"}`)
+		}
+	}
+}
+
+// asks running instance for a command and returns its answer
+func remoteCommand(command string) (string, error) {
+	conn, err := net.DialTimeout("unix", socketPath(), remoteTimeout)
+	if err != nil {
+		return "", errNoInstance
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(remoteTimeout))
+	if _, err := fmt.Fprintln(conn, command); err != nil {
+		return "", err
+	}
+	answer, err := bufio.NewReader(conn).ReadString('\n')
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(answer), nil
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"errors"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+var errNameTaken = errors.New("file with this name already exists")
+
+// renames file or directory and points queued tracks to the new path
+func (a appState) renameFile(oldPath string, newName string) appState {
+	newName = strings.TrimSpace(newName)
+	if newName == "" || newName == filepath.Base(oldPath) {
+		return a
+	}
+	if newName == "." || newName == ".." || strings.ContainsRune(newName, filepath.Separator) || strings.ContainsRune(newName, '/') {
+		a.status = "invalid name: " + newName
+		return a
+	}
+	if _, _, ok := splitArchivePath(filepath.Dir(oldPath)); ok {
+		a.status = "files inside archives can't be renamed"
+		return a
+	}
+	newPath := filepath.Join(filepath.Dir(oldPath), newName)
+	// rename silently replaces existing files on most systems
+	if _, err := os.Lstat(newPath); err == nil {
+		a.status = errNameTaken.Error()
+		return a
+	} else if !errors.Is(err, os.ErrNotExist) {
+		a.status = err.Error()
+		return a
+	}
+	if err := os.Rename(oldPath, newPath); err != nil {
+		log.Printf("failed to rename %s: %v", oldPath, err)
+		a.status = err.Error()
+		return a
+	}
+	a.tracksQueue.renamePath(oldPath, newPath)
+	a = a.navigate(a.updateChoices())
+	for i, choice := range a.choices {
+		if choice == newName {
+			a.cursor = i
+		}
+	}
+	a.status = "renamed to " + newName
+	return a
+}
+
+// updates paths of tracks that are the file or are inside the directory
+func (s *tracksQueue) renamePath(oldPath string, newPath string) {
+	rename := func(path string) string {
+		if path == oldPath {
+			return newPath
+		}
+		if rel, ok := strings.CutPrefix(path, oldPath+string(filepath.Separator)); ok {
+			return filepath.Join(newPath, rel)
+		}
+		return path
+	}
+	for i := range s.queue {
+		s.queue[i].path = rename(s.queue[i].path)
+	}
+	s.loop.path = rename(s.loop.path)
+}
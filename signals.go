@@ -0,0 +1,36 @@
+package main
+
+import (
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+// sent when the process is asked to terminate, for example by kill or on logout
+type shutdownMsg struct {
+	signal os.Signal
+}
+
+// passes SIGINT and SIGTERM to the program, so it quits the same way as with q.
+// returned function stops the handling
+func handleSignals() func() {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	done := make(chan struct{})
+	go func() {
+		for {
+			select {
+			case sig := <-signals:
+				log.Printf("received %v, quitting", sig)
+				program.Send(shutdownMsg{signal: sig})
+			case <-done:
+				return
+			}
+		}
+	}()
+	return func() {
+		signal.Stop(signals)
+		close(done)
+	}
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"math"
+
+	"github.com/gopxl/beep/v2"
+)
+
+var (
+	// skip silent parts at track boundaries
+	trimSilence bool
+	// amplitude below which samples are treated as silent
+	silenceThreshold = 0.01
+)
+
+// scans the whole stream and returns positions of the first audible sample
+// and the sample after the last audible one. stream is left at the first
+// audible sample. fully silent stream is not trimmed
+func silenceBounds(stream beep.StreamSeeker, threshold float64) (int, int, error) {
+	start, end := -1, 0
+	pos := 0
+	buf := make([][2]float64, 4096)
+	for {
+		n, ok := stream.Stream(buf)
+		for i := 0; i < n; i++ {
+			if math.Abs(buf[i][0]) <= threshold && math.Abs(buf[i][1]) <= threshold {
+				continue
+			}
+			if start == -1 {
+				start = pos + i
+			}
+			end = pos + i + 1
+		}
+		pos += n
+		if !ok {
+			break
+		}
+	}
+	if err := stream.Err(); err != nil {
+		return 0, 0, err
+	}
+	if start == -1 {
+		return 0, 0, stream.Seek(0)
+	}
+	return start, end, stream.Seek(start)
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+
+	"github.com/gopxl/beep/v2/speaker"
+)
+
+// files that are never played automatically, kept between runs
+var skipMarks = map[string]bool{}
+
+func skipMarksPath() string {
+	return statePath("skipped.json")
+}
+
+func loadSkipMarks() (map[string]bool, error) {
+	marks := map[string]bool{}
+	data, err := os.ReadFile(skipMarksPath())
+	if errors.Is(err, os.ErrNotExist) {
+		return marks, nil
+	}
+	if err != nil {
+		return marks, err
+	}
+	paths := []string{}
+	if err := json.Unmarshal(data, &paths); err != nil {
+		return marks, fmt.Errorf("%s: %w", skipMarksPath(), err)
+	}
+	for _, path := range paths {
+		marks[path] = true
+	}
+	return marks, nil
+}
+
+func saveSkipMarks(marks map[string]bool) error {
+	path := skipMarksPath()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	paths := make([]string, 0, len(marks))
+	for p := range marks {
+		paths = append(paths, p)
+	}
+	slices.Sort(paths)
+	data, err := json.MarshalIndent(paths, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0644)
+}
+
+// marks file as skipped or removes the mark. returns true if file is marked now
+func toggleSkipMark(path string) (bool, error) {
+	if skipMarks[path] {
+		delete(skipMarks, path)
+	} else {
+		skipMarks[path] = true
+	}
+	return skipMarks[path], saveSkipMarks(skipMarks)
+}
+
+// returns index of the first track starting from the index that
+// is not marked as skipped, or -1 if there is none
+func (s *tracksQueue) nextPlayable(from int) int {
+	for i := from; i < s.len(); i++ {
+		if !skipMarks[s.queue[i].path] {
+			return i
+		}
+	}
+	return -1
+}
+
+// makes the track at the index current. skipped tracks before it are treated as played
+func (s *tracksQueue) advanceTo(index int) {
+	for i := s.currentTrack; i < index; i++ {
+		s.queue[i].ended = true
+	}
+	s.currentTrack = index
+	s.stats.started(s.queue[index].path)
+	// the track after the new current one starts decoding ahead
+	speaker.Lock()
+	s.updatePrebuffers()
+	speaker.Unlock()
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// positions in seconds where playback of the files starts, for example
+// to skip intros. unlike the current position they are kept between runs
+var startOffsets = map[string]float64{}
+
+func startOffsetsPath() string {
+	return statePath("start-offsets.json")
+}
+
+func loadStartOffsets() (map[string]float64, error) {
+	offsets := map[string]float64{}
+	data, err := os.ReadFile(startOffsetsPath())
+	if errors.Is(err, os.ErrNotExist) {
+		return offsets, nil
+	}
+	if err != nil {
+		return offsets, err
+	}
+	if err := json.Unmarshal(data, &offsets); err != nil {
+		return offsets, fmt.Errorf("%s: %w", startOffsetsPath(), err)
+	}
+	return offsets, nil
+}
+
+func saveStartOffsets(offsets map[string]float64) error {
+	path := startOffsetsPath()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(offsets, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0644)
+}
+
+// sample where playback of the track starts and restarts
+func (t track) playStart() int {
+	return max(t.trimStart, t.startOffset)
+}
+
+// moves track to the start offset saved for its path. offsets outside
+// of the audible part are ignored
+func (t *track) applyStartOffset() error {
+	t.startOffset = 0
+	if seconds, ok := startOffsets[t.path]; ok {
+		start := t.format.SampleRate.N(time.Duration(seconds * float64(time.Second)))
+		if start > t.trimStart && start < t.stream.Len() && (t.trimEnd == 0 || start < t.trimEnd) {
+			t.startOffset = start
+		}
+	}
+	if t.stream.Position() == t.playStart() {
+		return nil
+	}
+	return t.stream.Seek(t.playStart())
+}
+
+// remembers position of the current track as its start for future plays.
+// returns description of the change
+func (s *tracksQueue) setStartOffset() (string, error) {
+	currentTrack, ok := s.getCurrentTrack()
+	if !ok {
+		return "", nil
+	}
+	pos, _, _ := s.position()
+	position := currentTrack.format.SampleRate.D(pos)
+	startOffsets[currentTrack.path] = position.Seconds()
+	if err := saveStartOffsets(startOffsets); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%s will start at %s", filepath.Base(currentTrack.path), formatDuration(position)), nil
+}
+
+// forgets start offset of the current track
+func (s *tracksQueue) clearStartOffset() (string, error) {
+	currentTrack, ok := s.getCurrentTrack()
+	if !ok {
+		return "", nil
+	}
+	if _, ok := startOffsets[currentTrack.path]; !ok {
+		return "no start offset set", nil
+	}
+	delete(startOffsets, currentTrack.path)
+	if err := saveStartOffsets(startOffsets); err != nil {
+		return "", err
+	}
+	return filepath.Base(currentTrack.path) + " will start from the beginning", nil
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func useStartOffsets(t *testing.T, offsets map[string]float64) {
+	t.Helper()
+	prev := startOffsets
+	startOffsets = offsets
+	t.Cleanup(func() { startOffsets = prev })
+}
+
+func TestStartOffsetKeepsStartSeekable(t *testing.T) {
+	useStartOffsets(t, map[string]float64{"/music/a.mp3": 2})
+	tr := testTrack("/music/a.mp3", int(basicSampleRate)*10)
+	if err := tr.applyStartOffset(); err != nil {
+		t.Fatal(err)
+	}
+	tr.resampled = tr.playback()
+	a := testState(t)
+	a.tracksQueue.addTrack(tr)
+	q := &a.tracksQueue
+	offset := basicSampleRate.N(2 * time.Second)
+
+	if pos, _, _ := q.position(); pos != offset {
+		t.Fatalf("track starts at %d, want %d", pos, offset)
+	}
+	if err := q.seek(0); err != nil {
+		t.Fatal(err)
+	}
+	if pos, _, _ := q.position(); pos != 0 {
+		t.Errorf("seek to 0 reached %d", pos)
+	}
+	q.restartTrack(0)
+	if pos, _, _ := q.position(); pos != offset {
+		t.Errorf("restart moved to %d, want %d", pos, offset)
+	}
+}
+
+func TestStartOffsetOfCueSheet(t *testing.T) {
+	dir := t.TempDir()
+	ogg, err := os.ReadFile("testdata/short.ogg")
+	if err != nil {
+		t.Fatal(err)
+	}
+	audioPath := filepath.Join(dir, "album.ogg")
+	cuePath := filepath.Join(dir, "album.cue")
+	sheet := "FILE \"album.ogg\" WAVE\n  TRACK 01 AUDIO\n    INDEX 01 00:00:00\n  TRACK 02 AUDIO\n    INDEX 01 00:00:20\n"
+	if err := os.WriteFile(audioPath, ogg, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(cuePath, []byte(sheet), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	useStartOffsets(t, map[string]float64{cuePath: 0.1, audioPath: 0.3})
+
+	tr, err := loadTrack(cuePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tr.stream.Close()
+	if want := tr.format.SampleRate.N(100 * time.Millisecond); tr.stream.Position() != want {
+		t.Errorf("cue sheet starts at %d, want %d saved for the sheet", tr.stream.Position(), want)
+	}
+}
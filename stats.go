@@ -0,0 +1,62 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"time"
+)
+
+// listening statistics of the current session
+type sessionStats struct {
+	// number of times each track was started
+	plays map[string]int
+	// total time something was playing
+	listened time.Duration
+	// something was playing on the previous observation
+	wasPlaying bool
+	lastTick   time.Time
+}
+
+func newSessionStats() *sessionStats {
+	return &sessionStats{plays: make(map[string]int)}
+}
+
+// counts a play of the track, called when it starts from the beginning.
+// pausing and resuming doesn't start it again
+func (st *sessionStats) started(path string) {
+	st.plays[path]++
+}
+
+// updates listening time with the state of the player at the moment
+func (st *sessionStats) observe(playing bool, now time.Time) {
+	if playing && st.wasPlaying && !st.lastTick.IsZero() {
+		st.listened += now.Sub(st.lastTick)
+	}
+	st.wasPlaying = playing
+	st.lastTick = now
+}
+
+func (st *sessionStats) String() string {
+	played := 0
+	mostPlayed, mostPlays := "", 0
+	for path, plays := range st.plays {
+		played += plays
+		if plays > mostPlays || plays == mostPlays && path < mostPlayed {
+			mostPlayed, mostPlays = path, plays
+		}
+	}
+	summary := fmt.Sprintf("played %d tracks, listened %s", played, formatDuration(st.listened))
+	if mostPlays > 1 {
+		summary += fmt.Sprintf(", most played: %s (%d times)", filepath.Base(mostPlayed), mostPlays)
+	}
+	return summary
+}
+
+// returns path of the track that is playing right now or empty string
+func (s *tracksQueue) playingPath() string {
+	currentTrack, ok := s.getCurrentTrack()
+	if !ok || currentTrack.ended || s.stopped || s.paused() {
+		return ""
+	}
+	return currentTrack.path
+}
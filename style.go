@@ -0,0 +1,36 @@
+package main
+
+import (
+	"os"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/muesli/termenv"
+)
+
+// styles of the UI, built from the theme
+var (
+	// files that can't be played or browsed
+	dimStyle lipgloss.Style
+	// entry of the playing track
+	playingStyle lipgloss.Style
+	// entries of the queued tracks
+	queuedStyle lipgloss.Style
+	// cursor of the lists
+	cursorStyle lipgloss.Style
+	// first line of the screen
+	headerStyle   lipgloss.Style
+	progressStyle lipgloss.Style
+)
+
+// see https://no-color.org
+func init() {
+	if os.Getenv("NO_COLOR") != "" {
+		lipgloss.SetColorProfile(termenv.Ascii)
+	}
+	themes[defaultThemeName].apply()
+}
+
+// returns true if file can be added to queue or browsed like a directory
+func isPlayable(name string) bool {
+	return isSupportedFormat(name) || fileFormat(name) == "cue" || isArchive(name)
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// tags of a file, fields are empty if file has no such tags
+type fileTags struct {
+	title  string
+	artist string
+	album  string
+	// position of the track in its album, 0 if unknown
+	number int
+	// raw ID3 tag of mp3 file, other tags like chapters are read from it
+	id3 id3Tag
+}
+
+// reads tags of the file. ID3 is used for mp3 files, vorbis comment for FLAC and OGG
+func readFileTags(trackPath string) (fileTags, error) {
+	f, err := openTrackFile(trackPath)
+	if err != nil {
+		return fileTags{}, err
+	}
+	defer f.Close()
+	var comments map[string]string
+	switch fileFormat(trackPath) {
+	case "mp3":
+		tag, err := readID3(f)
+		if err != nil {
+			return fileTags{}, err
+		}
+		return fileTags{
+			title:  tag.text("TIT2"),
+			artist: tag.text("TPE1"),
+			album:  tag.text("TALB"),
+			number: parseTrackNumber(tag.text("TRCK")),
+			id3:    tag,
+		}, nil
+	case "flac":
+		comments, err = readFLACComment(f)
+	case "ogg":
+		comments, err = readOggComment(f)
+	}
+	if err != nil {
+		return fileTags{}, err
+	}
+	return fileTags{
+		title:  comments["TITLE"],
+		artist: comments["ARTIST"],
+		album:  comments["ALBUM"],
+		number: parseTrackNumber(comments["TRACKNUMBER"]),
+	}, nil
+}
+
+// parses track number which may be written as "3/12"
+func parseTrackNumber(s string) int {
+	s, _, _ = strings.Cut(s, "/")
+	number, err := strconv.Atoi(strings.TrimSpace(s))
+	if err != nil {
+		return 0
+	}
+	return number
+}
+
+// reads title, artist, album and chapters of the track from its tags
+func (t *track) readTags() error {
+	tags, err := readFileTags(t.path)
+	if err != nil {
+		return err
+	}
+	t.title, t.artist, t.album = tags.title, tags.artist, tags.album
+	t.trackNum = tags.number
+	t.chapters = id3Chapters(tags.id3, t.format.SampleRate)
+	return nil
+}
+
+// name of the track shown to user. file name is used if track has no title
+func (t track) displayName() string {
+	switch {
+	case t.title == "":
+		return filepath.Base(t.path)
+	case t.artist != "":
+		return t.artist + " - " + t.title
+	}
+	return t.title
+}
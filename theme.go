@@ -0,0 +1,104 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+// colors of the UI parts. nil colors keep the terminal foreground
+type theme struct {
+	cursor   lipgloss.TerminalColor
+	playing  lipgloss.TerminalColor
+	queued   lipgloss.TerminalColor
+	dim      lipgloss.TerminalColor
+	header   lipgloss.TerminalColor
+	progress lipgloss.TerminalColor
+}
+
+const defaultThemeName = "default"
+
+// themes selected with the theme setting of the config
+var themes = map[string]theme{
+	// colors adapt to the background of the terminal, so text stays readable
+	// on both dark and light themes
+	defaultThemeName: {
+		playing: lipgloss.AdaptiveColor{Light: "#005FAF", Dark: "#5FD7FF"},
+		queued:  lipgloss.AdaptiveColor{Light: "#5F8700", Dark: "#AFD75F"},
+		dim:     lipgloss.AdaptiveColor{Light: "#9E9E9E", Dark: "#626262"},
+	},
+	"solarized": {
+		cursor:   lipgloss.Color("#CB4B16"),
+		playing:  lipgloss.Color("#268BD2"),
+		queued:   lipgloss.Color("#859900"),
+		dim:      lipgloss.Color("#93A1A1"),
+		header:   lipgloss.Color("#B58900"),
+		progress: lipgloss.Color("#2AA198"),
+	},
+	"gruvbox": {
+		cursor:   lipgloss.Color("#FE8019"),
+		playing:  lipgloss.Color("#83A598"),
+		queued:   lipgloss.Color("#B8BB26"),
+		dim:      lipgloss.Color("#928374"),
+		header:   lipgloss.Color("#FABD2F"),
+		progress: lipgloss.Color("#8EC07C"),
+	},
+	// no colors, only bold and faint text
+	"mono": {},
+}
+
+// names of the UI parts that can be colored in the colors setting of the config
+var themeParts = []string{"cursor", "playing", "queued", "dim", "header", "progress"}
+
+// returns theme with given name and colors replaced by the ones from overrides.
+// colors are hex values like "#FF8700" or ANSI color numbers
+func buildTheme(name string, overrides map[string]string) (theme, error) {
+	t, ok := themes[name]
+	if !ok {
+		names := make([]string, 0, len(themes))
+		for name := range themes {
+			names = append(names, name)
+		}
+		slices.Sort(names)
+		return t, fmt.Errorf("unknown theme %q, available themes: %s", name, strings.Join(names, ", "))
+	}
+	for part, value := range overrides {
+		color := lipgloss.Color(value)
+		switch part {
+		case "cursor":
+			t.cursor = color
+		case "playing":
+			t.playing = color
+		case "queued":
+			t.queued = color
+		case "dim":
+			t.dim = color
+		case "header":
+			t.header = color
+		case "progress":
+			t.progress = color
+		default:
+			return t, fmt.Errorf("unknown color %q, colors are set for %s", part, strings.Join(themeParts, ", "))
+		}
+	}
+	return t, nil
+}
+
+func colored(style lipgloss.Style, color lipgloss.TerminalColor) lipgloss.Style {
+	if color == nil {
+		return style
+	}
+	return style.Foreground(color)
+}
+
+// rebuilds styles of the UI from the theme
+func (t theme) apply() {
+	dimStyle = colored(lipgloss.NewStyle().Faint(true), t.dim)
+	playingStyle = colored(lipgloss.NewStyle().Bold(true), t.playing)
+	queuedStyle = colored(lipgloss.NewStyle(), t.queued)
+	cursorStyle = colored(lipgloss.NewStyle().Bold(true), t.cursor)
+	headerStyle = colored(lipgloss.NewStyle(), t.header)
+	progressStyle = colored(lipgloss.NewStyle(), t.progress)
+}
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"syscall"
+	"time"
+)
+
+var errTrashUnsupported = errors.New("trash is not supported on this system")
+
+// moves file to the user trash following the FreeDesktop trash specification.
+// see https://specifications.freedesktop.org/trash-spec/latest/
+func trashFile(path string) error {
+	if runtime.GOOS == "windows" || runtime.GOOS == "darwin" {
+		return errTrashUnsupported
+	}
+	path, err := filepath.Abs(path)
+	if err != nil {
+		return err
+	}
+	trashDir, err := homeTrashDir()
+	if err != nil {
+		return err
+	}
+	filesDir := filepath.Join(trashDir, "files")
+	infoDir := filepath.Join(trashDir, "info")
+	if err := os.MkdirAll(filesDir, 0700); err != nil {
+		return err
+	}
+	if err := os.MkdirAll(infoDir, 0700); err != nil {
+		return err
+	}
+	infoPath, trashedName, err := createTrashInfo(infoDir, path)
+	if err != nil {
+		return err
+	}
+	err = os.Rename(path, filepath.Join(filesDir, trashedName))
+	if err == nil {
+		return nil
+	}
+	os.Remove(infoPath)
+	// file is on another filesystem, gio knows how to find trash of that volume
+	if _, lookErr := exec.LookPath("gio"); lookErr == nil {
+		return exec.Command("gio", "trash", "--", path).Run()
+	}
+	return err
+}
+
+// removes file by moving it to trash. file is removed permanently only if force
+// is set and trash is not available, other failures are returned as they are.
+// reports whether the file was removed permanently
+func deleteFile(path string, force bool) (bool, error) {
+	err := trashFile(path)
+	// trash of another filesystem is reached only through gio
+	if force && (errors.Is(err, errTrashUnsupported) || errors.Is(err, syscall.EXDEV)) {
+		return true, os.Remove(path)
+	}
+	return false, err
+}
+
+func homeTrashDir() (string, error) {
+	dataHome := os.Getenv("XDG_DATA_HOME")
+	if !filepath.IsAbs(dataHome) {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", err
+		}
+		dataHome = filepath.Join(home, ".local", "share")
+	}
+	return filepath.Join(dataHome, "Trash"), nil
+}
+
+// atomically reserves name in trash by creating .trashinfo file for it
+func createTrashInfo(infoDir string, path string) (string, string, error) {
+	base := filepath.Base(path)
+	ext := filepath.Ext(base)
+	stem := strings.TrimSuffix(base, ext)
+	info := fmt.Sprintf("[Trash Info]\nPath=%s\nDeletionDate=%s\n",
+		(&url.URL{Path: path}).EscapedPath(),
+		time.Now().Format("2006-01-02T15:04:05"),
+	)
+	for i := 1; ; i++ {
+		name := base
+		if i > 1 {
+			name = fmt.Sprintf("%s.%d%s", stem, i, ext)
+		}
+		infoPath := filepath.Join(infoDir, name+".trashinfo")
+		f, err := os.OpenFile(infoPath, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+		if errors.Is(err, os.ErrExist) {
+			continue
+		}
+		if err != nil {
+			return "", "", err
+		}
+		_, err = f.WriteString(info)
+		closeErr := f.Close()
+		if err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			os.Remove(infoPath)
+			return "", "", err
+		}
+		return infoPath, name, nil
+	}
+}
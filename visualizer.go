@@ -0,0 +1,114 @@
+package main
+
+import (
+	"math"
+	"math/cmplx"
+	"strings"
+
+	"github.com/gopxl/beep/v2"
+	"github.com/gopxl/beep/v2/speaker"
+)
+
+// number of samples analyzed by the visualizer, must be a power of two
+const fftSize = 2048
+
+// frequency range shown by the visualizer
+const (
+	minBarFrequency = 40
+	maxBarFrequency = 16000
+	// magnitude shown as an empty bar
+	minBarDb = -60
+)
+
+var barLevels = []rune(" ▁▂▃▄▅▆▇█")
+
+// passes samples through and keeps the latest of them for the visualizer
+type sampleTap struct {
+	Streamer beep.Streamer
+	// ring buffer of mono samples
+	window [fftSize]float64
+	next   int
+}
+
+func (t *sampleTap) Stream(samples [][2]float64) (int, bool) {
+	n, ok := t.Streamer.Stream(samples)
+	for _, sample := range samples[:n] {
+		t.window[t.next] = (sample[0] + sample[1]) / 2
+		t.next = (t.next + 1) % fftSize
+	}
+	return n, ok
+}
+
+func (t *sampleTap) Err() error {
+	return t.Streamer.Err()
+}
+
+// returns the latest samples in the order they were played
+func (s *tracksQueue) recentSamples() []float64 {
+	samples := make([]float64, fftSize)
+	speaker.Lock()
+	n := copy(samples, s.tap.window[s.tap.next:])
+	copy(samples[n:], s.tap.window[:s.tap.next])
+	speaker.Unlock()
+	return samples
+}
+
+// in-place iterative radix-2 FFT, len(x) must be a power of two
+func fft(x []complex128) {
+	n := len(x)
+	for i, j := 1, 0; i < n; i++ {
+		bit := n >> 1
+		for ; j&bit != 0; bit >>= 1 {
+			j ^= bit
+		}
+		j ^= bit
+		if i < j {
+			x[i], x[j] = x[j], x[i]
+		}
+	}
+	for size := 2; size <= n; size <<= 1 {
+		step := cmplx.Exp(complex(0, -2*math.Pi/float64(size)))
+		for start := 0; start < n; start += size {
+			w := complex(1, 0)
+			for k := 0; k < size/2; k++ {
+				even, odd := x[start+k], w*x[start+k+size/2]
+				x[start+k] = even + odd
+				x[start+k+size/2] = even - odd
+				w *= step
+			}
+		}
+	}
+}
+
+// renders spectrum of the recent samples as a line of bars.
+// bars cover logarithmically spaced frequency bands
+func (s *tracksQueue) spectrumLine(bars int) string {
+	samples := s.recentSamples()
+	x := make([]complex128, fftSize)
+	for i, sample := range samples {
+		// hann window reduces leakage between bands
+		window := 0.5 - 0.5*math.Cos(2*math.Pi*float64(i)/float64(fftSize-1))
+		x[i] = complex(sample*window, 0)
+	}
+	fft(x)
+	binWidth := float64(basicSampleRate) / fftSize
+	maxFrequency := min(maxBarFrequency, float64(basicSampleRate)/2)
+	ratio := math.Pow(maxFrequency/minBarFrequency, 1/float64(bars))
+	var line strings.Builder
+	low := float64(minBarFrequency)
+	for range bars {
+		high := low * ratio
+		first := int(low / binWidth)
+		last := max(first, int(high/binWidth))
+		peak := 0.0
+		for bin := first; bin <= last && bin < fftSize/2; bin++ {
+			peak = max(peak, cmplx.Abs(x[bin]))
+		}
+		// full scale sine reaches a quarter of the window size with hann window
+		db := 20 * math.Log10(peak/(fftSize/4)+1e-12)
+		level := (db - minBarDb) / -minBarDb * float64(len(barLevels)-1)
+		line.WriteRune(barLevels[int(max(0, min(level, float64(len(barLevels)-1))))])
+		low = high
+	}
+	return line.String()
+}
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"strings"
+)
+
+var errBadTags = errors.New("malformed tags")
+
+// upper bound of the comment header, it may contain cover art
+const maxCommentSize = 16 << 20
+
+// parses vorbis comment used by FLAC and OGG. keys are case-insensitive,
+// so they are returned upper-cased
+func parseVorbisComment(data []byte) (map[string]string, error) {
+	r := bytes.NewReader(data)
+	readString := func() (string, error) {
+		var size uint32
+		if err := binary.Read(r, binary.LittleEndian, &size); err != nil {
+			return "", errBadTags
+		}
+		if int64(size) > int64(r.Len()) {
+			return "", errBadTags
+		}
+		s := make([]byte, size)
+		r.Read(s)
+		return string(s), nil
+	}
+	// vendor string goes first
+	if _, err := readString(); err != nil {
+		return nil, err
+	}
+	var count uint32
+	if err := binary.Read(r, binary.LittleEndian, &count); err != nil {
+		return nil, errBadTags
+	}
+	comments := make(map[string]string)
+	for range count {
+		comment, err := readString()
+		if err != nil {
+			return nil, err
+		}
+		key, value, ok := strings.Cut(comment, "=")
+		key = strings.ToUpper(key)
+		if _, seen := comments[key]; ok && !seen {
+			comments[key] = strings.TrimSpace(value)
+		}
+	}
+	return comments, nil
+}
+
+// reads vorbis comment from FLAC metadata blocks
+func readFLACComment(r io.Reader) (map[string]string, error) {
+	header := make([]byte, 4)
+	if _, err := io.ReadFull(r, header); err != nil || string(header) != "fLaC" {
+		return nil, errBadTags
+	}
+	for {
+		if _, err := io.ReadFull(r, header); err != nil {
+			return nil, err
+		}
+		last, blockType := header[0]&0x80 != 0, header[0]&0x7f
+		size := int64(header[1])<<16 | int64(header[2])<<8 | int64(header[3])
+		if blockType == 4 {
+			data := make([]byte, size)
+			if _, err := io.ReadFull(r, data); err != nil {
+				return nil, err
+			}
+			return parseVorbisComment(data)
+		}
+		if last {
+			return nil, nil
+		}
+		if _, err := io.CopyN(io.Discard, r, size); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// reads vorbis comment from the second packet of OGG stream
+func readOggComment(r io.Reader) (map[string]string, error) {
+	var packet []byte
+	packets := 0
+	header := make([]byte, 27)
+	for {
+		if _, err := io.ReadFull(r, header); err != nil || string(header[:4]) != "OggS" {
+			return nil, errBadTags
+		}
+		segments := make([]byte, header[26])
+		if _, err := io.ReadFull(r, segments); err != nil {
+			return nil, err
+		}
+		for _, size := range segments {
+			segment := make([]byte, size)
+			if _, err := io.ReadFull(r, segment); err != nil {
+				return nil, err
+			}
+			packet = append(packet, segment...)
+			if len(packet) > maxCommentSize {
+				return nil, errBadTags
+			}
+			// segment shorter than 255 bytes ends the packet
+			if size == 255 {
+				continue
+			}
+			packets++
+			if packets == 2 {
+				comment, ok := bytes.CutPrefix(packet, []byte("\x03vorbis"))
+				if !ok {
+					return nil, errBadTags
+				}
+				return parseVorbisComment(comment)
+			}
+			packet = nil
+		}
+	}
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"log"
+	"time"
+
+	"github.com/gopxl/beep/v2/speaker"
+)
+
+// ticks that are this far apart mean the process was not running, usually
+// because the machine was suspended
+const suspendGap = 5 * time.Second
+
+// playback that doesn't move for this long is considered stuck
+const stallTimeout = 3 * time.Second
+
+// checks that audio output keeps consuming samples. output device is often
+// lost when the machine sleeps
+type audioWatchdog struct {
+	lastTick time.Time
+	// track and its position on the last check
+	lastPath     string
+	lastPosition int
+	// when position stopped moving
+	stalledSince time.Time
+}
+
+// observes the player state and returns true if audio output was restarted
+func (s *tracksQueue) checkOutput(now time.Time) bool {
+	w := &s.watchdog
+	// monotonic clock stops while the machine sleeps, so wall clock is compared
+	now = now.Round(0)
+	gap := now.Sub(w.lastTick)
+	suspended := !w.lastTick.IsZero() && gap > suspendGap
+	w.lastTick = now
+	path := s.playingPath()
+	if path == "" {
+		w.lastPath = ""
+		w.stalledSince = time.Time{}
+		return false
+	}
+	position, _, _ := s.position()
+	if path != w.lastPath || position != w.lastPosition || suspended {
+		w.lastPath, w.lastPosition = path, position
+		w.stalledSince = now
+		if !suspended {
+			return false
+		}
+	}
+	// silence between albums doesn't move position of any track
+	if !suspended && now.Sub(w.stalledSince) < stallTimeout+trackGap {
+		return false
+	}
+	w.stalledSince = now
+	if suspended {
+		log.Printf("woke up after %s, restarting audio output", gap.Round(time.Second))
+	} else {
+		log.Printf("playback of %s is stuck, restarting audio output", path)
+	}
+	s.restartOutput()
+	return true
+}
+
+// audio output the queue plays to, replaced by a stub in tests
+type audioOutput interface {
+	Suspend() error
+	Resume() error
+	// drops streamers that are playing and samples buffered for them
+	Clear()
+}
+
+type speakerOutput struct{}
+
+func (speakerOutput) Suspend() error { return speaker.Suspend() }
+func (speakerOutput) Resume() error  { return speaker.Resume() }
+func (speakerOutput) Clear()         { speaker.Clear() }
+
+var output audioOutput = speakerOutput{}
+
+// suspends and resumes audio output, then continues playing from the current
+// position. this gets playback going after sleep or a stall, but doesn't reopen
+// a device that is gone: beep output can be initialized only once per process
+func (s *tracksQueue) restartOutput() {
+	if err := output.Suspend(); err != nil {
+		log.Print(err)
+	}
+	if err := output.Resume(); err != nil {
+		log.Print(err)
+	}
+	s.play()
+}
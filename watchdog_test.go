@@ -0,0 +1,82 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+// output that counts restarts instead of touching the speaker
+type stubOutput struct {
+	suspends int
+	resumes  int
+	clears   int
+}
+
+func (o *stubOutput) Suspend() error {
+	o.suspends++
+	return nil
+}
+
+func (o *stubOutput) Resume() error {
+	o.resumes++
+	return nil
+}
+
+func (o *stubOutput) Clear() {
+	o.clears++
+}
+
+func useStubOutput(t *testing.T) *stubOutput {
+	t.Helper()
+	stub := &stubOutput{}
+	prev := output
+	output = stub
+	t.Cleanup(func() { output = prev })
+	return stub
+}
+
+func TestCheckOutputAfterSuspend(t *testing.T) {
+	stub := useStubOutput(t)
+	a := testState(t, "/music/a.mp3")
+	start := time.Now()
+	if a.tracksQueue.checkOutput(start) {
+		t.Fatal("output restarted on the first check")
+	}
+	// position moves between regular ticks
+	a.tracksQueue.queue[0].stream.Seek(1000)
+	if a.tracksQueue.checkOutput(start.Add(tickInterval)) {
+		t.Fatal("output restarted while playing normally")
+	}
+	if !a.tracksQueue.checkOutput(start.Add(tickInterval + time.Minute)) {
+		t.Fatal("output not restarted after the machine woke up")
+	}
+	if stub.suspends != 1 || stub.resumes != 1 {
+		t.Errorf("suspends %d, resumes %d, want 1 and 1", stub.suspends, stub.resumes)
+	}
+}
+
+func TestCheckOutputStalled(t *testing.T) {
+	stub := useStubOutput(t)
+	a := testState(t, "/music/a.mp3")
+	now := time.Now()
+	restarted := false
+	for elapsed := time.Duration(0); elapsed < stallTimeout+trackGap+time.Second; elapsed += tickInterval {
+		restarted = restarted || a.tracksQueue.checkOutput(now.Add(elapsed))
+	}
+	if !restarted || stub.resumes != 1 {
+		t.Errorf("stuck playback: restarted %v, resumes %d, want one restart", restarted, stub.resumes)
+	}
+}
+
+func TestCheckOutputPaused(t *testing.T) {
+	stub := useStubOutput(t)
+	a := testState(t, "/music/a.mp3")
+	a.tracksQueue.pause()
+	now := time.Now()
+	for elapsed := time.Duration(0); elapsed < time.Minute; elapsed += tickInterval {
+		a.tracksQueue.checkOutput(now.Add(elapsed))
+	}
+	if stub.resumes != 0 {
+		t.Errorf("paused playback restarted %d times", stub.resumes)
+	}
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"log"
+	"sync"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/fsnotify/fsnotify"
+)
+
+// delay after the last change of directory before the browser is refreshed,
+// so copying many files causes one refresh
+const dirChangeDebounce = 300 * time.Millisecond
+
+// sent when files of the watched directory were changed
+type dirChangedMsg struct {
+	dir string
+}
+
+// watches the current directory of the browser
+type dirWatcher struct {
+	watcher *fsnotify.Watcher
+	clock   clock
+	mu      sync.Mutex
+	dir     string
+}
+
+// creates watcher, changes are reported once it is started. returns nil
+// if watching is not available, nil watcher does nothing
+func newWatcher(c clock) *dirWatcher {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		log.Printf("directory watching is disabled: %v", err)
+		return nil
+	}
+	return &dirWatcher{watcher: watcher, clock: c}
+}
+
+// starts reporting changes with send, for example program.Send
+func (w *dirWatcher) start(send func(tea.Msg)) {
+	if w == nil {
+		return
+	}
+	go w.run(send)
+}
+
+func (w *dirWatcher) run(send func(tea.Msg)) {
+	// nil until a change waits for the debounce
+	var debounce <-chan time.Time
+	var changed string
+	for {
+		select {
+		case _, ok := <-w.watcher.Events:
+			if !ok {
+				return
+			}
+			w.mu.Lock()
+			changed = w.dir
+			w.mu.Unlock()
+			// every change restarts the wait
+			debounce = w.clock.After(dirChangeDebounce)
+		case <-debounce:
+			debounce = nil
+			send(dirChangedMsg{dir: changed})
+		case err, ok := <-w.watcher.Errors:
+			if !ok {
+				return
+			}
+			log.Printf("directory watcher: %v", err)
+		}
+	}
+}
+
+// switches watching to the directory
+func (w *dirWatcher) watch(dir string) {
+	if w == nil {
+		return
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if dir == w.dir {
+		return
+	}
+	if w.dir != "" {
+		w.watcher.Remove(w.dir)
+	}
+	w.dir = dir
+	// directories inside archives don't exist on disk
+	if _, _, ok := splitArchivePath(dir); ok {
+		return
+	}
+	if err := w.watcher.Add(dir); err != nil {
+		log.Printf("failed to watch %s: %v", dir, err)
+	}
+}
+
+func (w *dirWatcher) Close() error {
+	if w == nil {
+		return nil
+	}
+	return w.watcher.Close()
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/fsnotify/fsnotify"
+)
+
+// waits until the watcher asks the clock for n waits
+func waitForWaiters(t *testing.T, c *fakeClock, n int) {
+	t.Helper()
+	for range 1000 {
+		c.mu.Lock()
+		waiting := len(c.waiters)
+		c.mu.Unlock()
+		if waiting >= n {
+			return
+		}
+		time.Sleep(time.Millisecond)
+	}
+	t.Fatalf("watcher didn't wait for %d debounces", n)
+}
+
+func TestWatcherDebouncesChanges(t *testing.T) {
+	c := newFakeClock()
+	events := make(chan fsnotify.Event)
+	errs := make(chan error)
+	w := &dirWatcher{watcher: &fsnotify.Watcher{Events: events, Errors: errs}, clock: c, dir: "/music"}
+	msgs := make(chan tea.Msg, 2)
+	w.start(func(msg tea.Msg) { msgs <- msg })
+	defer close(events)
+
+	events <- fsnotify.Event{Name: "/music/a.mp3", Op: fsnotify.Create}
+	waitForWaiters(t, c, 1)
+	c.Advance(dirChangeDebounce - time.Millisecond)
+	events <- fsnotify.Event{Name: "/music/a.mp3", Op: fsnotify.Write}
+	waitForWaiters(t, c, 2)
+	// the first wait ends, but the second change restarted it
+	c.Advance(time.Millisecond)
+	select {
+	case msg := <-msgs:
+		t.Fatalf("refreshed before the debounce: %v", msg)
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	c.Advance(dirChangeDebounce)
+	select {
+	case msg := <-msgs:
+		if msg != (dirChangedMsg{dir: "/music"}) {
+			t.Errorf("sent %v, want change of /music", msg)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("change wasn't reported after the debounce")
+	}
+}